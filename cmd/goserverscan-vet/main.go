@@ -0,0 +1,14 @@
+// Command goserverscan-vet runs the goserverscan analyzers standalone
+// or as a vet tool:
+//
+//	go vet -vettool=$(which goserverscan-vet) -rules.dir ./rules -vulns.db ./vulndb -csrf.middleware verifyCSRF -ratelimit.middleware throttle ./...
+package main
+
+import (
+	"github.com/simcap/auditools/goserverscan/audit"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(audit.Analyzers(nil, nil)...)
+}
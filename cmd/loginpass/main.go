@@ -0,0 +1,222 @@
+// Command loginpass tries usernames and passwords against the login
+// form or basic authentication of a site.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+	"github.com/simcap/auditools/loginpass"
+	"github.com/simcap/auditools/passwords"
+)
+
+var (
+	urlFlag          string
+	postURLFlag      string
+	ssrFlag          bool
+	postJSONFlag     bool
+	formFileFlag     string
+	basicAuthFlag    bool
+	usernameListFlag string
+	passwordListFlag string
+	passwordDepth    int
+	jitterFlag       int
+	waitTimeFlag     int
+	verboseFlag      bool
+	workersFlag      int
+	rateFlag         string
+	burstFlag        int
+	hostConcFlag     int
+)
+
+func main() {
+	flag.StringVar(&urlFlag, "url", "", "URL of the resource")
+	flag.StringVar(&postURLFlag, "post-url", "", "URL to post the form if different from page form")
+	flag.StringVar(&formFileFlag, "form-file", "", "JSON ready form to feed into a poster")
+	flag.BoolVar(&ssrFlag, "ssr", false, "Use SSR to get HTML page content")
+	flag.BoolVar(&postJSONFlag, "json", false, "Use JSON to post form")
+	flag.BoolVar(&basicAuthFlag, "basicauth", false, "Basic authentication mode only (need url param)")
+	flag.IntVar(&passwordDepth, "passdepth", 0, "Level of passwords generations & permutations")
+	flag.StringVar(&usernameListFlag, "usernames", "admin", "Comma separated list of given usernames")
+	flag.StringVar(&passwordListFlag, "passwords", "", "Comma separated list of given password (overwrite password generation)")
+	flag.IntVar(&waitTimeFlag, "wait", 5, "Wait time in seconds between 2 requests (0 by default with -rate)")
+	flag.IntVar(&jitterFlag, "jitter", 5, "Jitter interval in seconds to randomize wait time between requests (0 by default with -rate)")
+	flag.IntVar(&workersFlag, "workers", 1, "Number of concurrent workers, each waiting between its requests")
+	flag.StringVar(&rateFlag, "rate", "", "Rate limit across all workers as N/s, N/m or N/h (e.g. 30/m)")
+	flag.IntVar(&burstFlag, "burst", 1, "Requests allowed at once before the rate limit applies")
+	flag.IntVar(&hostConcFlag, "host-concurrency", 0, "Maximum requests in flight per host (0 for no limit)")
+	flag.BoolVar(&verboseFlag, "v", false, "Verbose mode")
+
+	flag.Parse()
+	log.SetFlags(0)
+
+	requests, per, err := parseRate(rateFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []loginpass.Option{
+		loginpass.WithLogger(log.New(os.Stdout, "", log.Ltime)),
+		loginpass.WithVerbose(verboseFlag),
+		loginpass.WithHostConcurrency(hostConcFlag),
+		loginpass.WithRateLimit(requests, per),
+		loginpass.WithBurst(burstFlag),
+	}
+
+	var poster loginpass.Poster
+	if basicAuthFlag {
+		if urlFlag == "" {
+			log.Fatal(errors.New("missing url param when using basic auth"))
+		}
+		poster = loginpass.NewBasicAuthPoster(urlFlag, opts...)
+	} else {
+		var postForm *loginpass.POST
+		if formFileFlag != "" {
+			f, err := os.Open(formFileFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := json.NewDecoder(f).Decode(&postForm); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			if postForm, err = createPOSTForm(urlFlag); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		log.Println("POST form")
+		printJSON(postForm)
+
+		if postJSONFlag {
+			postForm.ContentType = "application/json"
+		}
+		if postURLFlag != "" {
+			postForm.URL = postURLFlag
+			postForm.ActionPath = ""
+		}
+		poster = loginpass.NewFormPoster(postForm, opts...)
+	}
+
+	usernames := strings.Split(usernameListFlag, ",")
+	var pass []string
+	if passwordListFlag != "" {
+		pass = strings.Split(passwordListFlag, ",")
+	} else {
+		options := passwords.Options{OrgOrURL: urlFlag, Depth: passwordDepth}
+		pass = passwords.Generate(options)
+	}
+	opts = append(opts,
+		loginpass.WithUsernames(usernames...),
+		loginpass.WithPasswords(pass...),
+		loginpass.WithWorkers(workersFlag),
+	)
+
+	// The rate limit spaces requests, waits only apply when asked for.
+	rate := "none"
+	if rateFlag != "" {
+		rate = rateFlag
+		if !isFlagSet("wait") {
+			waitTimeFlag = 0
+		}
+		if !isFlagSet("jitter") {
+			jitterFlag = 0
+		}
+	}
+	opts = append(opts, loginpass.WithWait(time.Duration(waitTimeFlag)*time.Second, time.Duration(jitterFlag)*time.Second))
+	candidater := loginpass.NewCandidater(poster, opts...)
+
+	log.Printf("\nEstimated max time %d mins (wait time: %d, jitter: %d, workers: %d, rate: %s, usernames: %d, password count: %d)", int(candidater.EstimatedMaxTime().Minutes()), waitTimeFlag, jitterFlag, workersFlag, rate, len(usernames), len(pass))
+
+	if confirm() {
+		if err := candidater.Run(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Candidates: %v", candidater.Candidates())
+	}
+}
+
+func isFlagSet(name string) (set bool) {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseRate parses rates as N/s, N/m or N/h, N alone being per second.
+// The empty rate is no limit.
+func parseRate(rate string) (requests int, per time.Duration, err error) {
+	if rate == "" {
+		return 0, 0, nil
+	}
+	n, unit, _ := strings.Cut(rate, "/")
+	switch unit {
+	case "", "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return 0, 0, fmt.Errorf("invalid rate %q: unit is s, m or h", rate)
+	}
+	if requests, err = strconv.Atoi(n); err != nil || requests <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q: expected a positive number of requests", rate)
+	}
+	return requests, per, nil
+}
+
+// createPOSTForm returns the login form of the page at pageURL, as
+// rendered by a browser with -ssr.
+func createPOSTForm(pageURL string) (*loginpass.POST, error) {
+	if !ssrFlag {
+		return loginpass.FormFromURL(pageURL)
+	}
+	if pageURL == "" {
+		return nil, errors.New("create form: missing url")
+	}
+	body, err := SSR(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return loginpass.FormFromHTML(pageURL, strings.NewReader(body))
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", " ")
+	enc.Encode(v)
+}
+
+func SSR(url string) (content string, err error) {
+	security.SetIgnoreCertificateErrors(true)
+	ctx, cancel := chromedp.NewContext(context.Background())
+	defer cancel()
+
+	err = chromedp.Run(ctx, chromedp.Navigate(url), chromedp.InnerHTML("html", &content))
+	return
+}
+
+func confirm() bool {
+	var s string
+	fmt.Printf("\nRun (y/N)? ")
+	if _, err := fmt.Scan(&s); err != nil {
+		log.Fatal(err)
+	}
+
+	s = strings.TrimSpace(strings.ToLower(s))
+
+	return s == "y" || s == "yes"
+}
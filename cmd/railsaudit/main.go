@@ -69,7 +69,7 @@ func (d *distribution) print(title string, w io.Writer) {
 		lines = append(lines, fmt.Sprintf("%s | %s\n", count, strings.Repeat("*", normal)))
 	}
 	for _, l := range lines {
-		fmt.Fprintf(w, l)
+		fmt.Fprint(w, l)
 	}
 	fmt.Fprintln(w)
 }
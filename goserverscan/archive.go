@@ -0,0 +1,132 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isArchive reports whether path is a source archive -dir can scan.
+func isArchive(path string) bool {
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// unpackArchive extracts the zip or gzipped tar archive at path to a
+// temporary sandbox, and returns the sandbox and a func removing it.
+// Only regular files and directories are extracted, and none outside
+// of the sandbox.
+func unpackArchive(path string) (dir string, cleanup func(), err error) {
+	dir, err = os.MkdirTemp("", "goserverscan-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		err = unzip(path, dir)
+	} else {
+		err = untar(path, dir)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return dir, cleanup, nil
+}
+
+func unzip(path, dir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		switch mode := f.Mode(); {
+		case mode.IsDir():
+			if _, err := sandboxPath(dir, f.Name); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = extractFile(dir, f.Name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func untar(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err := extractFile(dir, hdr.Name, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractFile(dir, name string, r io.Reader) error {
+	path, err := sandboxPath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sandboxPath returns where the archive entry name is extracted in
+// dir, rejecting entries escaping it.
+func sandboxPath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry %s outside of the archive", name)
+	}
+	return path, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanArchive(t *testing.T) {
+	root, err := ioutil.TempDir("", "goserverscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	src := "package %s\n\nimport \"net/http\"\n\nfunc f() { http.Get(%q) }\n"
+	archive := filepath.Join(root, "client-v2.1.0.zip")
+	writeZip(t, archive, map[string]string{
+		"app/go.mod":                     "module example.com/app\n",
+		"app/main.go":                    fmt.Sprintf(src, "main", "a"),
+		"app/lib@v1.0.0/go.mod":          "module example.com/lib\n",
+		"app/lib@v1.0.0/internal/lib.go": fmt.Sprintf(src, "internal", "b"),
+	})
+
+	s := &scanner{workers: 2, driver: newDriver(audit.Analyzers(nil, nil))}
+	results, err := s.ScanRoots([]string{archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, res := range results {
+		if len(res.OutGoingCalls) == 0 {
+			continue
+		}
+		rel, _ := filepath.Rel(root, res.OutGoingCalls[0].Filename)
+		got = append(got, fmt.Sprintf("%t %s@%s %s", res.Root == archive, res.Module, res.Version, filepath.ToSlash(rel)))
+	}
+	want := []string{
+		"true example.com/app@v2.1.0 client-v2.1.0.zip/app/main.go",
+		"true example.com/lib@v1.0.0 client-v2.1.0.zip/app/lib@v1.0.0/internal/lib.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnpackArchiveOutsideSandbox(t *testing.T) {
+	root, err := ioutil.TempDir("", "goserverscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	archive := filepath.Join(root, "evil.zip")
+	writeZip(t, archive, map[string]string{"../../escaped.go": "package escaped\n"})
+	if _, _, err := unpackArchive(archive); err == nil || !strings.Contains(err.Error(), "outside of the archive") {
+		t.Fatalf("got %v, want error on entry outside of the archive", err)
+	}
+}
@@ -0,0 +1,40 @@
+// Package audit implements the goserverscan checks as analyzers of
+// golang.org/x/tools/go/analysis so that they run in goserverscan as
+// well as under go vet -vettool, multichecker or gopls.
+//
+// Analyzers report their findings as diagnostics whose category is
+// the ID of a registered Check.
+package audit
+
+import "golang.org/x/tools/go/analysis"
+
+// Analyzers returns all the goserverscan analyzers. The rules analyzer
+// applies rules when given, or the rules of its -dir flag otherwise.
+// Likewise the vulns analyzer uses db, or the database of its -db flag.
+func Analyzers(rules []*Rule, db *VulnDB) []*analysis.Analyzer {
+	rulesAnalyzer := RulesAnalyzer
+	if len(rules) > 0 {
+		rulesAnalyzer = NewRulesAnalyzer(rules)
+	}
+	vulnAnalyzer := VulnAnalyzer
+	if db != nil {
+		vulnAnalyzer = NewVulnAnalyzer(db)
+	}
+	return []*analysis.Analyzer{
+		RoutesAnalyzer,
+		HandlersAnalyzer,
+		ServicesAnalyzer,
+		WebSocketAnalyzer,
+		TokensAnalyzer,
+		LeaksAnalyzer,
+		RacesAnalyzer,
+		ConfigAnalyzer,
+		StaticAnalyzer,
+		CSRFAnalyzer,
+		InjectionAnalyzer,
+		RateLimitAnalyzer,
+		OutgoingCallsAnalyzer,
+		rulesAnalyzer,
+		vulnAnalyzer,
+	}
+}
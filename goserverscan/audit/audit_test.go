@@ -0,0 +1,275 @@
+package audit_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestRoutes(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.RoutesAnalyzer, "routes")
+	routes := results[0].Result.(*audit.Routes)
+
+	if got, want := len(routes.Routers), 9; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	type route struct {
+		path          string
+		methods       []string
+		middleware    []string
+		authenticated bool
+	}
+	var got []route
+	for _, r := range routes.Routers[6:] {
+		for _, rt := range r.Routes {
+			got = append(got, route{rt.Path, rt.Methods, append(r.Middleware, rt.Middleware...), rt.Authenticated})
+		}
+	}
+	want := []route{
+		{"/public", nil, []string{"logging", "logging"}, false},
+		{"/admin", []string{"POST", "PUT"}, []string{"logging", "checkKey"}, true},
+		{"/items/{id}", []string{"DELETE"}, []string{"requireAuth"}, true},
+		{"/traced", nil, []string{"trace"}, false},
+		{"/basic", nil, []string{"basic"}, true},
+		{"/scoped", nil, []string{`oauthScope("admin")`}, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestRouters(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.RoutesAnalyzer, "routers/api", "routers")
+	routes := results[1].Result.(*audit.Routes)
+
+	var got []string
+	for _, r := range routes.Routers {
+		var paths []string
+		for _, rt := range r.Routes {
+			paths = append(paths, rt.Path+fmt.Sprint(rt.Methods))
+		}
+		got = append(got, fmt.Sprintf("%s %v %v", r.Kind, r.Middleware, paths))
+	}
+	want := []string{
+		"gorilla/mux [] [/default[] /default/extra[]]",
+		"gorilla/mux [] [/api/items[GET] /api/local[] /api/users[] /api/extra[]]",
+		"gorilla/mux [] [/v1[] /v1/extra[]]",
+		"net/http [] [/admin/stats[]]",
+		"gorilla/mux [logging] [/[] /health[] /ping[]]",
+		"net/http [] [/public[] /more[]]",
+		"gorilla/mux [] [/checked[] /checked/extra[]]",
+		"gorilla/mux [] [/map/api[]]",
+		"gorilla/mux [] [/map/web[]]",
+		"net/http [] [/slice[]]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules, _, err := audit.LoadRules("testdata/rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), audit.NewRulesAnalyzer(rules), "rules")
+}
+
+func TestRulesBuiltinID(t *testing.T) {
+	dir := t.TempDir()
+	rule := "rules:\n  - id: csrf-missing\n    package: net/http\n    function: Handle\n"
+	if err := os.WriteFile(filepath.Join(dir, "rules.yml"), []byte(rule), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := audit.LoadRules(dir); err == nil || !strings.Contains(err.Error(), "built-in") {
+		t.Fatalf("got %v, want an error on the id of a built-in check", err)
+	}
+	if c, _ := audit.LookupCheck("csrf-missing"); c.Severity != audit.SeverityHigh {
+		t.Errorf("built-in check replaced: %+v", c)
+	}
+}
+
+func TestServices(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.ServicesAnalyzer, "services")
+	services := results[0].Result.(*audit.Routes)
+
+	var got []string
+	for _, r := range services.Routers {
+		for _, rt := range r.Routes {
+			got = append(got, fmt.Sprintf("%s %s %s %v %t", r.Kind, r.Mount, rt.Path, r.Middleware, rt.Authenticated))
+		}
+	}
+	want := []string{
+		"grpc  /Greeter/SayHello [unary:logRequests] false",
+		"grpc  /Greeter/SayGoodbye [unary:logRequests] false",
+		"grpc  /Greeter/SayHello [unary:logRequests unary:checkToken] true",
+		"grpc  /Greeter/SayGoodbye [unary:logRequests unary:checkToken] true",
+		"grpc  /Greeter/SayHello [] false",
+		"grpc  /Greeter/SayGoodbye [] false",
+		"grpc  /Admin/Shutdown [unary:checkToken] true",
+		"graphql/gqlgen /query Query.User [] false",
+		"graphql/gqlgen /query Query.Users [] false",
+		"graphql/graph-gophers /graphql Hello [requireAuth] true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.WebSocketAnalyzer, "websocket")
+	routes := results[0].Pass.ResultOf[audit.RoutesAnalyzer].(*audit.Routes)
+
+	for _, r := range routes.Routers[0].Routes {
+		if !r.WebSocket {
+			t.Fatalf("route %s not marked as WebSocket", r.Path)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), audit.TokensAnalyzer, "tokens")
+}
+
+func TestLeaks(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), audit.LeaksAnalyzer, "leaks")
+}
+
+func TestRaces(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), audit.RacesAnalyzer, "races")
+}
+
+func TestConfig(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.ConfigAnalyzer, "config")
+
+	var got []string
+	for _, k := range results[0].Result.([]*audit.ConfigKey) {
+		got = append(got, fmt.Sprintf("%s %s %q %v", k.Source, k.Name, k.Default, k.Flows))
+	}
+	want := []string{
+		`flag addr ":8080" [listen]`,
+		`flag debug "false" []`,
+		`env APP_DATABASE_URL "" []`,
+		`env APP_TIMEOUT "30" []`,
+		`file /etc/app/config.yaml "" []`,
+		`env TLS_CERT "server.crt" [tls]`,
+		`env TLS_KEY "server.key" [tls]`,
+		`env SERVER_NAME "" [tls]`,
+		`viper cors.origin "*" [cors]`,
+		`env SESSION_SECRET "" [secret]`,
+		`env LOG_LEVEL "info" []`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestVulns(t *testing.T) {
+	db, _, err := audit.LoadVulnDB("testdata/vulndb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), audit.NewVulnAnalyzer(db), "vulns")
+}
+
+func TestHandlers(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.HandlersAnalyzer, "handlers")
+
+	var got []string
+	for _, h := range results[0].Result.([]*audit.Handler) {
+		got = append(got, fmt.Sprintf("%s %s %v %v", h.Kind, h.Name, h.Routes, h.Servers))
+	}
+	want := []string{
+		"type api [/api/] [:8443]",
+		"type files [/static/] []",
+		"factory server.handleIndex [/] []",
+		"factory newHealth [/health] []",
+		"middleware requireAuth [/admin] [:8443]",
+		"type admin [/admin] []",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	routes := results[0].Pass.ResultOf[audit.RoutesAnalyzer].(*audit.Routes)
+	for _, r := range routes.Routers[0].Routes {
+		if r.Path == "/api/" && (len(r.Middleware) != 1 || r.Middleware[0] != `http.StripPrefix("/api")` || r.Handler != "&api{}") {
+			t.Errorf("got middleware %v and handler %s, want StripPrefix unwrapped", r.Middleware, r.Handler)
+		}
+		if r.HandlerLocation == "" {
+			t.Errorf("missing handler location of %s", r.Path)
+		}
+	}
+}
+
+func TestStatic(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.StaticAnalyzer, "static")
+
+	var got []string
+	for _, m := range results[0].Result.([]*audit.StaticMount) {
+		got = append(got, fmt.Sprintf("%s %s %s %v", m.Kind, m.Prefix, m.Root, m.Files))
+	}
+	want := []string{
+		"net/http /public/ public [public/.htpasswd public/css/app.css]",
+		"net/http / . [assets/.hidden assets/index.html public/.htpasswd public/css/app.css static.go templates/index.tmpl]",
+		"net/http /assets/ assets [assets/index.html]",
+		"net/http /all/ all:assets [assets/.hidden assets/index.html]",
+		"net/http /views/ templates [templates/index.tmpl]",
+		"net/http /custom/ noListing{http.Dir(\"public\")} []",
+		"gin /static ./public/css [public/css/app.css]",
+		"gin /browse public [public/.htpasswd public/css/app.css]",
+		"echo /site assets [assets/.hidden assets/index.html]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	if err := audit.CSRFAnalyzer.Flags.Set("middleware", "verifyOrigin"); err != nil {
+		t.Fatal(err)
+	}
+	defer audit.CSRFAnalyzer.Flags.Set("middleware", "")
+	analysistest.Run(t, analysistest.TestData(), audit.CSRFAnalyzer, "csrf", "csrfserver")
+}
+
+func TestInputs(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), audit.RoutesAnalyzer, "inputs")
+	routes := results[0].Result.(*audit.Routes)
+
+	var got []string
+	for _, r := range routes.Routers {
+		for _, rt := range r.Routes {
+			got = append(got, fmt.Sprintf("%s %v", rt.Path, rt.Inputs))
+		}
+	}
+	want := []string{
+		"/items/{id} [path:id query:sort query:page query:filter header:X-Request-ID cookie:session]",
+		"/login [body:username body:password body:remember body:Scopes]",
+		"/upload [form:name form:csrf_token]",
+		"/users/{user:[0-9]+}/files/{path} [path:user path:path]",
+		"/raw [body:username body:password body:remember body:Scopes]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestInjection(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), audit.InjectionAnalyzer, "injection")
+}
+
+func TestRateLimit(t *testing.T) {
+	if err := audit.RateLimitAnalyzer.Flags.Set("middleware", "slowDown"); err != nil {
+		t.Fatal(err)
+	}
+	defer audit.RateLimitAnalyzer.Flags.Set("middleware", "")
+	analysistest.Run(t, analysistest.TestData(), audit.RateLimitAnalyzer, "ratelimit")
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Check describes the findings reported under a rule ID. Analyzers
+// report diagnostics with the rule ID as category so that drivers can
+// look up severity and confidence with LookupCheck.
+type Check struct {
+	ID         string
+	Severity   Severity
+	Confidence Confidence
+	CWE        string
+
+	// userRule is set on the checks of user-defined rules, which may
+	// be loaded again but must not replace built-in checks.
+	userRule bool
+}
+
+var (
+	checksMu sync.RWMutex
+	checks   = make(map[string]*Check)
+)
+
+// RegisterCheck makes c available to LookupCheck. Registering a check
+// with the ID of a previous one replaces it.
+func RegisterCheck(c *Check) *Check {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	checks[c.ID] = c
+	return c
+}
+
+func LookupCheck(id string) (*Check, bool) {
+	checksMu.RLock()
+	defer checksMu.RUnlock()
+	c, ok := checks[id]
+	return c, ok
+}
+
+// report emits a diagnostic for c on node unless an ignore directive
+// applies to the line of node.
+func report(pass *analysis.Pass, node ast.Node, c *Check, format string, args ...interface{}) {
+	if isSuppressed(pass, node.Pos(), c.ID) {
+		return
+	}
+	pass.Report(analysis.Diagnostic{
+		Pos:      node.Pos(),
+		End:      node.End(),
+		Category: c.ID,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+const ignoreDirective = "//auditools:ignore"
+
+// isSuppressed reports whether a `//auditools:ignore <rule-id> reason`
+// comment is on the line of pos, as a trailing comment, or on the line
+// above it.
+func isSuppressed(pass *analysis.Pass, pos token.Pos, id string) bool {
+	file := fileOf(pass, pos)
+	if file == nil {
+		return false
+	}
+	line := pass.Fset.Position(pos).Line
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, ignoreDirective) {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(c.Text, ignoreDirective))
+			if len(fields) == 0 || (fields[0] != id && fields[0] != "all") {
+				continue
+			}
+			if l := pass.Fset.Position(c.Slash).Line; l == line || l == line-1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fileOf(pass *analysis.Pass, pos token.Pos) *ast.File {
+	for _, f := range pass.Files {
+		if f.FileStart <= pos && pos <= f.FileEnd {
+			return f
+		}
+	}
+	return nil
+}
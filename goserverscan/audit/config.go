@@ -0,0 +1,606 @@
+package audit
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// ConfigAnalyzer lists the configuration surface of a package:
+// environment variables, command line flags, viper and envconfig keys
+// and configuration files, with their constant defaults and the
+// security relevant places their values flow into.
+var ConfigAnalyzer = &analysis.Analyzer{
+	Name:             "config",
+	Doc:              "list environment variables, flags and configuration files read",
+	Run:              runConfig,
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf([]*ConfigKey(nil)),
+}
+
+// Flows of configuration values into security relevant places.
+const (
+	FlowListen = "listen"
+	FlowTLS    = "tls"
+	FlowSecret = "secret"
+	FlowCORS   = "cors"
+)
+
+const (
+	viperPath      = "github.com/spf13/viper"
+	envconfigPath  = "github.com/kelseyhightower/envconfig"
+	caarlosEnvPath = "github.com/caarlos0/env"
+	godotenvPath   = "github.com/joho/godotenv"
+)
+
+var (
+	flagPaths       = []string{"flag", "github.com/spf13/pflag"}
+	caarlosEnvPaths = []string{caarlosEnvPath, caarlosEnvPath + "/v6", caarlosEnvPath + "/v11"}
+
+	flagFuncRE   = regexp.MustCompile(`^(Bool|Duration|Float64|Int|Int64|String|StringSlice|IntSlice|Uint|Uint64)(Var)?(P)?$`)
+	configFileRE = regexp.MustCompile(`(?i)conf|\.(json|ya?ml|toml|ini|env|properties)$`)
+)
+
+// configValue is a configuration key with the expressions holding its
+// value, within fn or, when fn is nil, in the whole package.
+type configValue struct {
+	key     *ConfigKey
+	holders map[string]bool
+	fn      *ast.FuncDecl
+}
+
+func runConfig(pass *analysis.Pass) (interface{}, error) {
+	var values []*configValue
+	defaults := make(map[string]string)
+	helpers := make(map[types.Object]*envHelper)
+
+	for _, file := range pass.Files {
+		var fn *ast.FuncDecl
+		ast.Inspect(file, func(n ast.Node) bool {
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				fn = decl
+			}
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if isPkgSelector(pass.TypesInfo, file, sel, viperPath, "SetDefault") && len(call.Args) == 2 {
+				defaults[exprValue(call.Args[0])] = constValue(pass, call.Args[1])
+				return true
+			}
+			values = append(values, configKeys(pass, file, fn, call, sel, helpers)...)
+			return true
+		})
+	}
+	values = append(values, envHelperCalls(pass, helpers)...)
+
+	keys := make([]*ConfigKey, 0, len(values))
+	for _, v := range values {
+		if v.key.Source == "viper" && v.key.Default == "" {
+			v.key.Default = defaults[v.key.Name]
+		}
+		flows := make(map[string]bool)
+		if sensitiveNameRE.MatchString(v.key.Name) {
+			flows[FlowSecret] = true
+		}
+		v.flows(pass, flows)
+		for flow := range flows {
+			v.key.Flows = append(v.key.Flows, flow)
+		}
+		sort.Strings(v.key.Flows)
+		keys = append(keys, v.key)
+	}
+	return keys, nil
+}
+
+func configKeys(pass *analysis.Pass, file *ast.File, fn *ast.FuncDecl, call *ast.CallExpr, sel *ast.SelectorExpr, helpers map[types.Object]*envHelper) []*configValue {
+	newValue := func(source string, name ast.Expr, def ast.Expr) *configValue {
+		v := &configValue{
+			key: &ConfigKey{
+				Snippet: NewSnippet(pass.Fset, call),
+				Source:  source,
+				Name:    exprValue(name),
+			},
+			holders: map[string]bool{types.ExprString(call): true},
+			fn:      fn,
+		}
+		if def != nil {
+			v.key.Default = constValue(pass, def)
+		}
+		v.hold(pass, file, call)
+		return v
+	}
+
+	switch {
+	case isPkgSelector(pass.TypesInfo, file, sel, "os", "Getenv"), isPkgSelector(pass.TypesInfo, file, sel, "os", "LookupEnv"):
+		if len(call.Args) != 1 {
+			return nil
+		}
+		if h := newEnvHelper(pass, fn, call.Args[0]); h != nil {
+			helpers[pass.TypesInfo.Defs[fn.Name]] = h
+			return nil
+		}
+		v := newValue("env", call.Args[0], nil)
+		v.key.Default = envDefault(pass, file, call)
+		return []*configValue{v}
+
+	case isFlagFunc(pass, file, sel):
+		m := flagFuncRE.FindStringSubmatch(sel.Sel.Name)
+		args := call.Args
+		var holder ast.Expr
+		if m[2] != "" && len(args) > 0 {
+			holder, args = args[0], args[1:]
+		}
+		if m[3] != "" && len(args) > 1 {
+			args = append(args[:1:1], args[2:]...)
+		}
+		if len(args) < 2 {
+			return nil
+		}
+		v := newValue("flag", args[0], args[1])
+		if u, ok := holder.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			v.holders[types.ExprString(u.X)] = true
+			v.fn = scopeOf(pass, u.X, fn)
+		}
+		return []*configValue{v}
+
+	case isViperGet(pass, file, sel):
+		if len(call.Args) != 1 {
+			return nil
+		}
+		return []*configValue{newValue("viper", call.Args[0], nil)}
+
+	case isPkgSelector(pass.TypesInfo, file, sel, envconfigPath, "Process"), isPkgSelector(pass.TypesInfo, file, sel, envconfigPath, "MustProcess"):
+		if len(call.Args) != 2 {
+			return nil
+		}
+		prefix := strings.ToUpper(exprValue(call.Args[0]))
+		return structKeys(pass, fn, call, call.Args[1], func(field *types.Var, tag reflect.StructTag) (string, string) {
+			name := tag.Get("envconfig")
+			if name == "" {
+				name = strings.ToUpper(field.Name())
+			}
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			return name, tag.Get("default")
+		})
+
+	case isAnyPkgSelector(pass, file, sel, caarlosEnvPaths, "Parse"):
+		if len(call.Args) < 1 {
+			return nil
+		}
+		return structKeys(pass, fn, call, call.Args[0], func(field *types.Var, tag reflect.StructTag) (string, string) {
+			name, _, _ := strings.Cut(tag.Get("env"), ",")
+			return name, tag.Get("envDefault")
+		})
+
+	case isPkgSelector(pass.TypesInfo, file, sel, godotenvPath, "Load"), isPkgSelector(pass.TypesInfo, file, sel, godotenvPath, "Overload"):
+		if len(call.Args) == 0 {
+			v := newValue("file", call.Fun, nil)
+			v.key.Name = ".env"
+			return []*configValue{v}
+		}
+		var values []*configValue
+		for _, arg := range call.Args {
+			values = append(values, newValue("file", arg, nil))
+		}
+		return values
+
+	case isPkgSelector(pass.TypesInfo, file, sel, viperPath, "SetConfigFile"), isPkgSelector(pass.TypesInfo, file, sel, viperPath, "SetConfigName"):
+		if len(call.Args) != 1 {
+			return nil
+		}
+		return []*configValue{newValue("file", call.Args[0], nil)}
+
+	case isPkgSelector(pass.TypesInfo, file, sel, "os", "ReadFile"), isPkgSelector(pass.TypesInfo, file, sel, "os", "Open"),
+		isPkgSelector(pass.TypesInfo, file, sel, "io/ioutil", "ReadFile"):
+		if len(call.Args) != 1 || !configFileRE.MatchString(path.Base(exprValue(call.Args[0]))) {
+			return nil
+		}
+		return []*configValue{newValue("file", call.Args[0], nil)}
+	}
+	return nil
+}
+
+func isFlagFunc(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr) bool {
+	if !flagFuncRE.MatchString(sel.Sel.Name) {
+		return false
+	}
+	if isAnyPkgSelector(pass, file, sel, flagPaths, sel.Sel.Name) {
+		return true
+	}
+	// Flag sets: fs.String("addr", ":8080", "listen address")
+	t := typeOf(pass.TypesInfo, sel.X)
+	return t != nil && (isNamed(t, "flag", "FlagSet") || isNamed(t, "github.com/spf13/pflag", "FlagSet"))
+}
+
+func isViperGet(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr) bool {
+	if !strings.HasPrefix(sel.Sel.Name, "Get") {
+		return false
+	}
+	if isPkgSelector(pass.TypesInfo, file, sel, viperPath, sel.Sel.Name) {
+		return true
+	}
+	t := typeOf(pass.TypesInfo, sel.X)
+	return t != nil && isNamed(t, viperPath, "Viper")
+}
+
+// structKeys lists the fields of the struct decoded from the
+// environment in spec, as in envconfig.Process("app", &spec). Fields
+// are named from their tag by key.
+func structKeys(pass *analysis.Pass, fn *ast.FuncDecl, call *ast.CallExpr, spec ast.Expr, key func(*types.Var, reflect.StructTag) (name, def string)) (values []*configValue) {
+	u, ok := spec.(*ast.UnaryExpr)
+	if !ok || u.Op != token.AND {
+		return nil
+	}
+	t := typeOf(pass.TypesInfo, u.X)
+	if t == nil {
+		return nil
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		name, def := key(field, reflect.StructTag(st.Tag(i)))
+		if name == "" || !field.Exported() {
+			continue
+		}
+		values = append(values, &configValue{
+			key: &ConfigKey{
+				Snippet: NewSnippet(pass.Fset, call),
+				Source:  "env",
+				Name:    name,
+				Default: def,
+			},
+			holders: map[string]bool{types.ExprString(u.X) + "." + field.Name(): true},
+			fn:      scopeOf(pass, u.X, fn),
+		})
+	}
+	return values
+}
+
+// hold records the variable the value of call is assigned to, through
+// conversions as in port, err := strconv.Atoi(os.Getenv("PORT")).
+func (v *configValue) hold(pass *analysis.Pass, file *ast.File, call *ast.CallExpr) {
+	path, _ := astutil.PathEnclosingInterval(file, call.Pos(), call.End())
+	for _, n := range path[1:] {
+		switch node := n.(type) {
+		case *ast.CallExpr, *ast.ParenExpr, *ast.StarExpr, *ast.BinaryExpr, *ast.UnaryExpr, *ast.IndexExpr:
+			continue
+		case *ast.AssignStmt:
+			if len(node.Lhs) > 0 && extractIdent(node.Lhs[0]) != "_" {
+				v.holders[types.ExprString(node.Lhs[0])] = true
+				v.fn = scopeOf(pass, node.Lhs[0], v.fn)
+			}
+		case *ast.ValueSpec:
+			if len(node.Names) > 0 {
+				v.holders[node.Names[0].Name] = true
+				v.fn = scopeOf(pass, node.Names[0], v.fn)
+			}
+		case *ast.KeyValueExpr:
+			// Config{Addr: os.Getenv("ADDR")}
+			v.holders[extractIdent(node.Key)] = true
+		}
+		return
+	}
+}
+
+// scopeOf returns fn unless the variable at the root of expr is
+// declared at package level.
+func scopeOf(pass *analysis.Pass, expr ast.Expr, fn *ast.FuncDecl) *ast.FuncDecl {
+	ident, ok := rootExpr(expr).(*ast.Ident)
+	if !ok {
+		return fn
+	}
+	obj := pass.TypesInfo.Uses[ident]
+	if obj == nil {
+		obj = pass.TypesInfo.Defs[ident]
+	}
+	if obj != nil && obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() {
+		return nil
+	}
+	return fn
+}
+
+// envDefault returns the constant default of an environment variable
+// read in call, set by cmp.Or(os.Getenv("X"), "default") or by
+//
+//	x := os.Getenv("X")
+//	if x == "" {
+//		x = "default"
+//	}
+func envDefault(pass *analysis.Pass, file *ast.File, call *ast.CallExpr) string {
+	path, _ := astutil.PathEnclosingInterval(file, call.Pos(), call.End())
+	if len(path) < 2 {
+		return ""
+	}
+	switch parent := path[1].(type) {
+	case *ast.CallExpr:
+		if calleeName(parent) == "Or" && len(parent.Args) == 2 && parent.Args[0] == call {
+			return constValue(pass, parent.Args[1])
+		}
+	case *ast.AssignStmt:
+		name := extractIdent(parent.Lhs[0])
+		if name == "" || len(path) < 3 {
+			return ""
+		}
+		block, ok := path[2].(*ast.BlockStmt)
+		if !ok {
+			return ""
+		}
+		for _, stmt := range block.List {
+			ifStmt, ok := stmt.(*ast.IfStmt)
+			if !ok || ifStmt.Pos() < parent.End() {
+				continue
+			}
+			cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+			if !ok || cond.Op != token.EQL || extractIdent(cond.X) != name || exprValue(cond.Y) != "" {
+				continue
+			}
+			for _, s := range ifStmt.Body.List {
+				if assign, ok := s.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 && extractIdent(assign.Lhs[0]) == name {
+					return constValue(pass, assign.Rhs[0])
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// envHelper is a function reading the environment variable named by
+// its key parameter, with a fallback as its default parameter, such
+// as func getenv(key, fallback string) string.
+type envHelper struct {
+	key, def int
+}
+
+func newEnvHelper(pass *analysis.Pass, fn *ast.FuncDecl, arg ast.Expr) *envHelper {
+	name := extractIdent(arg)
+	if fn == nil || name == "" || fn.Type.Params == nil {
+		return nil
+	}
+	h := &envHelper{key: -1, def: -1}
+	var i int
+	for _, field := range fn.Type.Params.List {
+		for _, param := range field.Names {
+			switch {
+			case param.Name == name:
+				h.key = i
+			case h.def < 0:
+				h.def = i
+			}
+			i++
+		}
+	}
+	if h.key < 0 || pass.TypesInfo.Defs[fn.Name] == nil {
+		return nil
+	}
+	return h
+}
+
+// envHelperCalls lists the environment variables read through helpers.
+func envHelperCalls(pass *analysis.Pass, helpers map[types.Object]*envHelper) (values []*configValue) {
+	if len(helpers) == 0 {
+		return nil
+	}
+	for _, file := range pass.Files {
+		var fn *ast.FuncDecl
+		ast.Inspect(file, func(n ast.Node) bool {
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				fn = decl
+			}
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			h := helpers[pass.TypesInfo.Uses[ident]]
+			if h == nil || h.key >= len(call.Args) {
+				return true
+			}
+			v := &configValue{
+				key: &ConfigKey{
+					Snippet: NewSnippet(pass.Fset, call),
+					Source:  "env",
+					Name:    exprValue(call.Args[h.key]),
+				},
+				holders: map[string]bool{types.ExprString(call): true},
+				fn:      fn,
+			}
+			if h.def >= 0 && h.def < len(call.Args) {
+				v.key.Default = constValue(pass, call.Args[h.def])
+			}
+			v.hold(pass, file, call)
+			values = append(values, v)
+			return true
+		})
+	}
+	return values
+}
+
+// constValue returns the value of expr when it is a constant.
+func constValue(pass *analysis.Pass, expr ast.Expr) string {
+	if !isConstant(pass.TypesInfo, expr) {
+		return ""
+	}
+	return exprValue(expr)
+}
+
+// flows adds to flows the security relevant places the value reaches
+// within its scope, following assignments and decoding calls.
+func (v *configValue) flows(pass *analysis.Pass, flows map[string]bool) {
+	var bodies []ast.Node
+	if v.fn != nil {
+		bodies = append(bodies, v.fn.Body)
+	} else {
+		for _, file := range pass.Files {
+			bodies = append(bodies, file)
+		}
+	}
+	for _, body := range bodies {
+		if body == nil {
+			continue
+		}
+		holders := make(map[string]bool)
+		for h := range v.holders {
+			holders[h] = true
+		}
+		propagateHolders(body, holders)
+		for _, s := range configSinks(pass, body) {
+			if holdsAny(holders, s.expr) {
+				flows[s.flow] = true
+			}
+		}
+	}
+}
+
+func propagateHolders(body ast.Node, holders map[string]bool) {
+	add := func(expr ast.Expr) bool {
+		s := types.ExprString(expr)
+		if expr == nil || holders[s] || extractIdent(expr) == "_" {
+			return false
+		}
+		holders[s] = true
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				for i, rhs := range node.Rhs {
+					if holdsAny(holders, rhs) && i < len(node.Lhs) {
+						changed = add(node.Lhs[i]) || changed
+					}
+				}
+				if len(node.Rhs) == 1 && len(node.Lhs) > 1 && holdsAny(holders, node.Rhs[0]) {
+					changed = add(node.Lhs[0]) || changed
+				}
+			case *ast.ValueSpec:
+				for i, value := range node.Values {
+					if holdsAny(holders, value) && i < len(node.Names) {
+						changed = add(node.Names[i]) || changed
+					}
+				}
+			case *ast.KeyValueExpr:
+				if holdsAny(holders, node.Value) {
+					changed = add(node.Key) || changed
+				}
+			case *ast.CallExpr:
+				// yaml.Unmarshal(data, &cfg)
+				if holdsAny(holders, node.Fun) || anyHolds(holders, node.Args) {
+					for _, arg := range node.Args {
+						if u, ok := arg.(*ast.UnaryExpr); ok && u.Op == token.AND {
+							changed = add(u.X) || changed
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// holdsAny reports whether expr uses one of holders.
+func holdsAny(holders map[string]bool, expr ast.Expr) (found bool) {
+	if expr == nil {
+		return false
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Ident:
+			found = holders[node.Name]
+		case *ast.SelectorExpr:
+			found = holders[types.ExprString(node)] || holders[node.Sel.Name]
+		case *ast.CallExpr:
+			found = holders[types.ExprString(node)]
+		}
+		return !found
+	})
+	return found
+}
+
+func anyHolds(holders map[string]bool, exprs []ast.Expr) bool {
+	for _, expr := range exprs {
+		if holdsAny(holders, expr) {
+			return true
+		}
+	}
+	return false
+}
+
+type configSink struct {
+	flow string
+	expr ast.Expr
+}
+
+// configSinks returns the listen addresses, TLS settings, secrets and
+// CORS origins set in node.
+func configSinks(pass *analysis.Pass, node ast.Node) (sinks []configSink) {
+	add := func(flow string, exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			sinks = append(sinks, configSink{flow, expr})
+		}
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.CompositeLit:
+			typ := types.ExprString(node.Type)
+			for _, elt := range node.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				switch key := extractIdent(kv.Key); {
+				case typ == "http.Server" && key == "Addr":
+					add(FlowListen, kv.Value)
+				case typ == "tls.Config":
+					add(FlowTLS, kv.Value)
+				case strings.HasPrefix(key, "AllowedOrigin") || strings.HasPrefix(key, "AllowOrigin"):
+					add(FlowCORS, kv.Value)
+				}
+			}
+		case *ast.CallExpr:
+			args := node.Args
+			switch name := calleeName(node); {
+			case name == "ListenAndServe" && len(args) > 0:
+				add(FlowListen, args[0])
+			case name == "ListenAndServeTLS" && len(args) == 4:
+				add(FlowListen, args[0])
+				add(FlowTLS, args[1:3]...)
+			case name == "ListenAndServeTLS" || name == "ServeTLS" || name == "LoadX509KeyPair":
+				add(FlowTLS, args...)
+			case name == "Listen" && len(args) > 1:
+				add(FlowListen, args[1])
+			case name == "NewCookieStore" || name == "SignedString" || name == "NewFilesystemStore":
+				add(FlowSecret, args...)
+			case name == "New" && len(args) == 2 && strings.HasPrefix(types.ExprString(node.Fun), "hmac."):
+				add(FlowSecret, args[1])
+			case name == "AllowedOrigins":
+				add(FlowCORS, args...)
+			case (name == "Set" || name == "Add") && len(args) == 2 && strings.EqualFold(exprValue(args[0]), "Access-Control-Allow-Origin"):
+				add(FlowCORS, args[1])
+			}
+		}
+		return true
+	})
+	return sinks
+}
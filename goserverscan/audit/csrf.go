@@ -0,0 +1,235 @@
+package audit
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// CSRFAnalyzer reports the routes accepting state-changing methods in
+// packages with cookie sessions that no CSRF middleware wraps, either
+// on the route, its router or the whole server. Handlers decoding JSON
+// without checking the content type are reported apart: cross-site
+// forms can post text/plain bodies to them without a preflight.
+var CSRFAnalyzer = &analysis.Analyzer{
+	Name:             "csrf",
+	Doc:              "report state-changing routes with cookie sessions lacking CSRF protection",
+	Run:              runCSRF,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var (
+	checkCSRFMissing = RegisterCheck(&Check{
+		ID:         "csrf-missing",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-352",
+	})
+	checkCSRFJSONContentType = RegisterCheck(&Check{
+		ID:         "csrf-json-content-type",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-352",
+	})
+)
+
+var csrfMiddlewareFlag string
+
+func init() {
+	CSRFAnalyzer.Flags.StringVar(&csrfMiddlewareFlag, "middleware", "", "Comma separated names of functions protecting handlers against CSRF")
+}
+
+// csrfNameRE matches the middleware of gorilla/csrf and nosurf, and
+// functions named after CSRF.
+var csrfNameRE = regexp.MustCompile(`(?i)csrf|xsrf|nosurf`)
+
+var sessionPaths = []string{
+	gorillaSessionsPath,
+	"github.com/alexedwards/scs/v2",
+	"github.com/gin-contrib/sessions",
+}
+
+// unsafeMethods are the methods changing state.
+var unsafeMethods = map[string]bool{"POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+
+func runCSRF(pass *analysis.Pass) (interface{}, error) {
+	if !usesCookieSessions(pass) || serverProtected(pass, isCSRFMiddleware) {
+		return nil, nil
+	}
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+	for _, router := range routes.Routers {
+		if anyMiddleware(router.Middleware, isCSRFMiddleware) {
+			continue
+		}
+		for _, route := range router.Routes {
+			handler, ok := routes.handlers[route]
+			if !ok || anyMiddleware(route.Middleware, isCSRFMiddleware) {
+				continue
+			}
+			bodies := handlerBodies(pass, handler)
+			methods, changes := stateChanging(route, bodies)
+			if !changes {
+				continue
+			}
+			on := strings.TrimSpace(strings.Join(methods, ",") + " " + route.Path)
+			switch {
+			case anyBody(bodies, decodesJSON) && !anyBody(bodies, readsForm):
+				if !anyBody(bodies, checksContentType) {
+					report(pass, handler, checkCSRFJSONContentType, "%s decodes JSON without checking the Content-Type, cross-site forms can post it with cookie sessions", on)
+				}
+			default:
+				report(pass, handler, checkCSRFMissing, "%s accepts cookie-authenticated requests without CSRF protection", on)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// usesCookieSessions reports whether the package reads or sets
+// cookies, or uses a session library.
+func usesCookieSessions(pass *analysis.Pass) (found bool) {
+	for _, file := range pass.Files {
+		if importsAnyPath(file, sessionPaths) {
+			return true
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				switch calleeName(call) {
+				case "Cookie", "Cookies", "SetCookie":
+					found = true
+				}
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// serverProtected reports whether a middleware, as reported by
+// isMiddleware, wraps a whole router, as in
+// http.ListenAndServe(addr, csrf.Protect(key)(r)), and so every route
+// of the package.
+func serverProtected(pass *analysis.Pass, isMiddleware func(string) bool) (found bool) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if _, handler := servedHandler(pass, file, n); handler != nil {
+				ast.Inspect(handler, func(n ast.Node) bool {
+					if call, ok := n.(*ast.CallExpr); ok && isMiddleware(exprValue(call.Fun)) {
+						found = true
+					}
+					return !found
+				})
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// isCSRFMiddleware reports whether the middleware mw, as rendered in
+// routes, protects against CSRF.
+func isCSRFMiddleware(mw string) bool {
+	if i := strings.Index(mw, "("); i >= 0 {
+		mw = mw[:i]
+	}
+	if csrfNameRE.MatchString(mw) {
+		return true
+	}
+	name := mw[strings.LastIndex(mw, ".")+1:]
+	for _, configured := range strings.Split(csrfMiddlewareFlag, ",") {
+		if configured = strings.TrimSpace(configured); configured != "" && configured == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stateChanging returns the state-changing methods of route, from
+// its registration or else from the methods its handler compares the
+// request method with, and whether it accepts any.
+func stateChanging(route *Route, bodies []handlerBody) (methods []string, ok bool) {
+	if len(route.Methods) > 0 {
+		for _, m := range route.Methods {
+			if unsafeMethods[strings.ToUpper(m)] {
+				methods = append(methods, m)
+			}
+		}
+		return methods, len(methods) > 0
+	}
+	for _, b := range bodies {
+		ast.Inspect(b.body, func(n ast.Node) bool {
+			var method string
+			switch node := n.(type) {
+			case *ast.BasicLit:
+				method = exprValue(node)
+			case *ast.SelectorExpr:
+				// http.MethodPost
+				if m, found := strings.CutPrefix(node.Sel.Name, "Method"); found && extractIdent(node.X) == "http" {
+					method = strings.ToUpper(m)
+				}
+			}
+			if unsafeMethods[method] {
+				methods = appendUnique(methods, method)
+			}
+			return true
+		})
+	}
+	return methods, len(methods) > 0
+}
+
+func anyBody(bodies []handlerBody, f func(*ast.BlockStmt) bool) bool {
+	for _, b := range bodies {
+		if f(b.body) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodesJSON(body *ast.BlockStmt) (found bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && extractIdent(sel.X) == "json" && (sel.Sel.Name == "NewDecoder" || sel.Sel.Name == "Unmarshal") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func readsForm(body *ast.BlockStmt) (found bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			switch sel.Sel.Name {
+			case "ParseForm", "ParseMultipartForm", "FormValue", "PostFormValue", "PostForm", "Form", "MultipartForm":
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// checksContentType reports whether body reads the Content-Type of
+// the request, expecting it to reject other types than JSON.
+func checksContentType(body *ast.BlockStmt) (found bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.BasicLit:
+			if strings.EqualFold(exprValue(node), "Content-Type") {
+				found = true
+			}
+		case *ast.SelectorExpr:
+			if node.Sel.Name == "ParseMediaType" {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
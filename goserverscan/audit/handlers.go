@@ -0,0 +1,266 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// HandlersAnalyzer lists the types implementing http.Handler and the
+// functions returning handlers, with the routes and servers mounting
+// them through wrappers such as http.StripPrefix.
+var HandlersAnalyzer = &analysis.Analyzer{
+	Name:             "handlers",
+	Doc:              "list http.Handler implementations and factories with the routes and servers mounting them",
+	Run:              runHandlers,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf([]*Handler(nil)),
+}
+
+// Kinds of handlers.
+const (
+	HandlerType       = "type"
+	HandlerFactory    = "factory"
+	HandlerMiddleware = "middleware"
+)
+
+func runHandlers(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	var handlers []*Handler
+	byName := make(map[string]*Handler)
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			var kind, name string
+			switch {
+			case isServeHTTP(fn):
+				kind, name = HandlerType, receiverTypeName(fn)
+			case returnsHandler(pass, file, fn):
+				kind, name = HandlerFactory, declName(fn)
+				if takesHandler(pass, file, fn) {
+					kind = HandlerMiddleware
+				}
+			default:
+				continue
+			}
+			if name == "" || byName[name] != nil {
+				continue
+			}
+			// The signature only, without the body.
+			sig := &ast.FuncDecl{Recv: fn.Recv, Name: fn.Name, Type: fn.Type}
+			h := &Handler{Snippet: NewSnippet(pass.Fset, sig), Name: name, Kind: kind}
+			handlers = append(handlers, h)
+			byName[name] = h
+		}
+	}
+	// Types getting ServeHTTP from an embedded field.
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok || byName[ts.Name.Name] != nil {
+					continue
+				}
+				obj := pass.TypesInfo.Defs[ts.Name]
+				if obj == nil {
+					continue
+				}
+				if m, _, _ := types.LookupFieldOrMethod(obj.Type(), true, pass.Pkg, "ServeHTTP"); m == nil {
+					continue
+				}
+				pos := pass.Fset.Position(ts.Pos())
+				h := &Handler{
+					Snippet: Snippet{Code: "type " + ts.Name.Name, Filename: pos.Filename, Line: pos.Line},
+					Name:    ts.Name.Name,
+					Kind:    HandlerType,
+				}
+				handlers = append(handlers, h)
+				byName[h.Name] = h
+			}
+		}
+	}
+	if len(handlers) == 0 {
+		return handlers, nil
+	}
+
+	for _, router := range routes.Routers {
+		for _, route := range router.Routes {
+			if h := byName[handlerName(pass, routes.handlers[route])]; h != nil {
+				h.Routes = appendUnique(h.Routes, route.Path)
+			}
+		}
+	}
+	for _, h := range handlers {
+		if h.Kind == HandlerMiddleware {
+			name := h.Name[strings.LastIndex(h.Name, ".")+1:]
+			for _, route := range routes.RoutesWithMiddleware(name) {
+				h.Routes = appendUnique(h.Routes, route.Path)
+			}
+		}
+	}
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			addr, handler := servedHandler(pass, file, n)
+			for handler != nil {
+				if h := byName[handlerName(pass, handler)]; h != nil {
+					h.Servers = appendUnique(h.Servers, addr)
+				}
+				call, ok := handler.(*ast.CallExpr)
+				if !ok {
+					break
+				}
+				if _, inner, ok := unwrapStdWrapper(pass.TypesInfo, file, call); ok {
+					handler = inner
+				} else if len(call.Args) == 1 {
+					handler = call.Args[0]
+				} else {
+					break
+				}
+			}
+			return true
+		})
+	}
+	return handlers, nil
+}
+
+// servedHandler returns the address and handler of a server started
+// in n, as in http.ListenAndServe(":8080", h) or
+// &http.Server{Addr: addr, Handler: h}.
+func servedHandler(pass *analysis.Pass, file *ast.File, n ast.Node) (addr string, handler ast.Expr) {
+	switch node := n.(type) {
+	case *ast.CallExpr:
+		sel, ok := node.Fun.(*ast.SelectorExpr)
+		if !ok || len(node.Args) < 2 {
+			return "", nil
+		}
+		i := 1
+		switch sel.Sel.Name {
+		case "ListenAndServeTLS":
+			i = 3
+		case "ListenAndServe", "Serve", "ServeTLS":
+		default:
+			return "", nil
+		}
+		if i < len(node.Args) && isPkgSelector(pass.TypesInfo, file, sel, "net/http", sel.Sel.Name) {
+			return exprValue(node.Args[0]), node.Args[i]
+		}
+	case *ast.CompositeLit:
+		sel, ok := node.Type.(*ast.SelectorExpr)
+		if !ok || !isPkgSelector(pass.TypesInfo, file, sel, "net/http", "Server") {
+			return "", nil
+		}
+		if h := fieldValue(node, "Handler"); h != nil {
+			addr := "server"
+			if a := fieldValue(node, "Addr"); a != nil {
+				addr = exprValue(a)
+			}
+			return addr, h
+		}
+	}
+	return "", nil
+}
+
+// handlerName returns the name of the handler type, or of the handler
+// factory called, in expr.
+func handlerName(pass *analysis.Pass, expr ast.Expr) string {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		if decl := resolveFuncDecl(pass, call.Fun); decl != nil {
+			return declName(decl)
+		}
+		return ""
+	}
+	return handlerTypeName(pass, expr)
+}
+
+// handlerTypeName returns the name of the package type of expr, such
+// as apiHandler in &apiHandler{db} or h of type *apiHandler.
+func handlerTypeName(pass *analysis.Pass, expr ast.Expr) string {
+	if u, ok := expr.(*ast.UnaryExpr); ok {
+		expr = u.X
+	}
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		return extractIdent(lit.Type)
+	}
+	t := typeOf(pass.TypesInfo, expr)
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() == pass.Pkg {
+		return named.Obj().Name()
+	}
+	return ""
+}
+
+// serveHTTPDecl returns the ServeHTTP method of the package type name.
+func serveHTTPDecl(pass *analysis.Pass, name string) *ast.FuncDecl {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && isServeHTTP(fn) && receiverTypeName(fn) == name {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+func isServeHTTP(fn *ast.FuncDecl) bool {
+	return fn.Recv != nil && fn.Name.Name == "ServeHTTP" && fn.Type.Params != nil && fn.Type.Params.NumFields() == 2
+}
+
+// returnsHandler reports whether fn returns a single http.Handler or
+// http.HandlerFunc.
+func returnsHandler(pass *analysis.Pass, file *ast.File, fn *ast.FuncDecl) bool {
+	results := fn.Type.Results
+	if results == nil || results.NumFields() != 1 {
+		return false
+	}
+	return isHandlerIface(pass, file, results.List[0].Type)
+}
+
+// takesHandler reports whether fn takes a handler to wrap.
+func takesHandler(pass *analysis.Pass, file *ast.File, fn *ast.FuncDecl) bool {
+	for _, field := range fn.Type.Params.List {
+		if isHandlerIface(pass, file, field.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHandlerIface(pass *analysis.Pass, file *ast.File, expr ast.Expr) bool {
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		return isPkgSelector(pass.TypesInfo, file, sel, "net/http", "Handler") ||
+			isPkgSelector(pass.TypesInfo, file, sel, "net/http", "HandlerFunc")
+	}
+	return false
+}
+
+// declName names functions and methods, as server.routes.
+func declName(fn *ast.FuncDecl) string {
+	if recv := receiverTypeName(fn); recv != "" {
+		return recv + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// InjectionAnalyzer reports server-side template injection, templates
+// parsed from request or database data and template functions exposing
+// the OS, and methods dispatched by reflection on request data.
+var InjectionAnalyzer = &analysis.Analyzer{
+	Name:             "injection",
+	Doc:              "report server-side template injection and unsafe reflection",
+	Run:              runInjection,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var (
+	checkTemplateInjection = RegisterCheck(&Check{
+		ID:         "template-injection",
+		Severity:   SeverityCritical,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-1336",
+	})
+	checkTemplateOSFuncs = RegisterCheck(&Check{
+		ID:         "template-os-funcs",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-78",
+	})
+	checkReflectDispatch = RegisterCheck(&Check{
+		ID:         "reflect-method-from-request",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-470",
+	})
+)
+
+var templatePaths = []string{"text/template", "html/template"}
+
+// osFuncs are the functions of package os that template functions must
+// not expose, in addition to the whole of os/exec.
+var osFuncs = map[string]bool{
+	"Chmod": true, "Chown": true, "Create": true, "Exit": true, "Open": true,
+	"OpenFile": true, "ReadDir": true, "ReadFile": true, "Remove": true,
+	"RemoveAll": true, "Rename": true, "Setenv": true, "StartProcess": true,
+	"Symlink": true, "WriteFile": true,
+}
+
+func runInjection(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	for _, file := range pass.Files {
+		var req, db sources
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.FuncDecl:
+				req, db = requestSources(node), databaseSources(pass.TypesInfo, node)
+			case *ast.CallExpr:
+				sel, ok := node.Fun.(*ast.SelectorExpr)
+				if !ok || len(node.Args) != 1 {
+					return true
+				}
+				switch {
+				case sel.Sel.Name == "Parse" && isTemplate(pass, file, sel.X):
+					switch {
+					case req.contains(node.Args[0]):
+						report(pass, node, checkTemplateInjection, "template parsed from request data%s", onRoutes(routesReaching(pass, routes, file, node)))
+					case db.contains(node.Args[0]):
+						report(pass, node, checkTemplateInjection, "template parsed from database data%s", onRoutes(routesReaching(pass, routes, file, node)))
+					}
+				case sel.Sel.Name == "Funcs" && isTemplate(pass, file, sel.X):
+					checkTemplateFuncs(pass, file, node.Args[0])
+				case sel.Sel.Name == "MethodByName" && isReflectValue(pass, sel.X) && req.contains(node.Args[0]):
+					report(pass, node, checkReflectDispatch, "method called by reflection is chosen from request data%s", onRoutes(routesReaching(pass, routes, file, node)))
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// isTemplate reports whether expr is a text/template or html/template
+// Template, by its type or else as a template.New call.
+func isTemplate(pass *analysis.Pass, file *ast.File, expr ast.Expr) bool {
+	if t := typeOf(pass.TypesInfo, expr); t != nil {
+		return isNamed(t, "text/template", "Template") || isNamed(t, "html/template", "Template")
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && isAnyPkgSelector(pass, file, sel, templatePaths, "New")
+}
+
+// isReflectValue reports whether expr is a reflect.Value or Type whose
+// MethodByName is called.
+func isReflectValue(pass *analysis.Pass, expr ast.Expr) bool {
+	t := typeOf(pass.TypesInfo, expr)
+	if t == nil {
+		return false
+	}
+	return isNamed(t, "reflect", "Value") || isNamed(t, "reflect", "Type")
+}
+
+// checkTemplateFuncs reports the functions of the FuncMap funcs, a
+// literal or a package variable, running commands or touching files.
+func checkTemplateFuncs(pass *analysis.Pass, file *ast.File, funcs ast.Expr) {
+	if ident, ok := funcs.(*ast.Ident); ok {
+		if v, ok := pass.TypesInfo.Uses[ident].(*types.Var); ok {
+			funcs = varValue(pass, v)
+		}
+	}
+	lit, ok := funcs.(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if name := exposedOSFunc(pass, file, kv.Value); name != "" {
+			report(pass, kv, checkTemplateOSFuncs, "template function %s exposes %s to templates", exprValue(kv.Key), name)
+		}
+	}
+}
+
+// exposedOSFunc returns the os or os/exec function expr is, or calls
+// when it is a function of the package.
+func exposedOSFunc(pass *analysis.Pass, file *ast.File, expr ast.Expr) (name string) {
+	isOSFunc := func(sel *ast.SelectorExpr) bool {
+		return isPkgSelector(pass.TypesInfo, file, sel, "os/exec", sel.Sel.Name) ||
+			osFuncs[sel.Sel.Name] && isPkgSelector(pass.TypesInfo, file, sel, "os", sel.Sel.Name)
+	}
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		if isOSFunc(sel) {
+			return exprValue(sel)
+		}
+		return ""
+	}
+	body := funcBody(pass, expr)
+	if body == nil {
+		return ""
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && isOSFunc(sel) {
+				name = exprValue(sel)
+			}
+		}
+		return name == ""
+	})
+	return name
+}
@@ -0,0 +1,236 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// pathVarRE matches the variables of route paths: {id}, {id:[0-9]+}
+// and {path...}.
+var pathVarRE = regexp.MustCompile(`\{([^}:.$]+)[^}]*\}`)
+
+// handlerInputs returns the inputs of route: the variables of its path
+// and what its handler reads from requests, as parameters, headers,
+// cookies and the fields of decoded JSON bodies.
+func handlerInputs(pass *analysis.Pass, route *Route, handler ast.Expr) (inputs []*Input) {
+	add := func(in, name, typ string) {
+		if name == "" {
+			return
+		}
+		for _, input := range inputs {
+			if input.In == in && input.Name == name {
+				return
+			}
+		}
+		inputs = append(inputs, &Input{In: in, Name: name, Type: typ})
+	}
+	for _, m := range pathVarRE.FindAllStringSubmatch(route.Path, -1) {
+		add(InPath, m[1], "string")
+	}
+
+	// FormValue reads the query string, and the body of requests
+	// sending one.
+	formValue := InQuery
+	for _, m := range route.Methods {
+		if unsafeMethods[strings.ToUpper(m)] {
+			formValue = InForm
+		}
+	}
+
+	for _, b := range handlerBodies(pass, handler) {
+		reqs := requestParams(b.params)
+		ast.Inspect(b.body, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.FuncLit); ok {
+				for name := range requestParams(lit.Type) {
+					reqs[name] = true
+				}
+			}
+			return true
+		})
+		// Variables holding r.URL.Query() and mux.Vars(r).
+		queries, vars := make(sources), make(sources)
+		ast.Inspect(b.body, func(n ast.Node) bool {
+			if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == len(assign.Rhs) {
+				for i, rhs := range assign.Rhs {
+					switch {
+					case isQueryCall(reqs, rhs):
+						queries.taint(assign.Lhs[i])
+					case isMuxVarsCall(rhs):
+						vars.taint(assign.Lhs[i])
+					}
+				}
+			}
+			return true
+		})
+
+		ast.Inspect(b.body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.IndexExpr:
+				key := exprValue(node.Index)
+				switch {
+				case isMuxVarsCall(node.X) || vars.contains(node.X) && isIdent(node.X):
+					add(InPath, key, "string")
+				case queries.contains(node.X) && isIdent(node.X):
+					add(InQuery, key, "string")
+				}
+			case *ast.CallExpr:
+				sel, ok := node.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				var key string
+				if len(node.Args) > 0 {
+					key = exprValue(node.Args[0])
+				}
+				switch {
+				case reqs.contains(sel.X) && isIdent(sel.X):
+					switch sel.Sel.Name {
+					case "PathValue":
+						add(InPath, key, "string")
+					case "FormValue":
+						add(formValue, key, "string")
+					case "PostFormValue":
+						add(InForm, key, "string")
+					case "Cookie":
+						add(InCookie, key, "string")
+					}
+				case sel.Sel.Name == "Get" || sel.Sel.Name == "Values":
+					switch {
+					case isQueryCall(reqs, sel.X) || queries.contains(sel.X) && isIdent(sel.X):
+						add(InQuery, key, "string")
+					case isRequestField(reqs, sel.X, "Header"):
+						add(InHeader, key, "string")
+					case isRequestField(reqs, sel.X, "PostForm"), isRequestField(reqs, sel.X, "Form"):
+						add(InForm, key, "string")
+					}
+				case sel.Sel.Name == "Decode" && decodesRequestBody(reqs, sel.X),
+					sel.Sel.Name == "Unmarshal" && extractIdent(sel.X) == "json" && len(node.Args) == 2:
+					for _, field := range jsonFields(pass, node.Args) {
+						add(InBody, field[0], field[1])
+					}
+				}
+			}
+			return true
+		})
+	}
+	return inputs
+}
+
+// requestParams returns the *http.Request parameters of ft.
+func requestParams(ft *ast.FuncType) sources {
+	s := make(sources)
+	s.addRequestParams(ft)
+	return s
+}
+
+func isIdent(expr ast.Expr) bool {
+	_, ok := expr.(*ast.Ident)
+	return ok
+}
+
+// isRequestField reports whether expr is r.<name> with r a request.
+func isRequestField(reqs sources, expr ast.Expr, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == name && isIdent(sel.X) && reqs.contains(sel.X)
+}
+
+// isQueryCall reports whether expr is r.URL.Query().
+func isQueryCall(reqs sources, expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Query" && isRequestField(reqs, sel.X, "URL")
+}
+
+func isMuxVarsCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && isSelectorExpr(sel, "mux", "Vars")
+}
+
+// decodesRequestBody reports whether expr is json.NewDecoder(r.Body).
+func decodesRequestBody(reqs sources, expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && isSelectorExpr(sel, "json", "NewDecoder") && isRequestField(reqs, call.Args[0], "Body")
+}
+
+// jsonFields returns the names and JSON types of the fields of the
+// struct the last of args points to, as decoded by encoding/json.
+func jsonFields(pass *analysis.Pass, args []ast.Expr) (fields [][2]string) {
+	if len(args) == 0 {
+		return nil
+	}
+	t := typeOf(pass.TypesInfo, args[len(args)-1])
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		name := field.Name()
+		if tag, ok := reflect.StructTag(st.Tag(i)).Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		fields = append(fields, [2]string{name, jsonType(field.Type())})
+	}
+	return fields
+}
+
+// jsonType returns the JSON type values of t are encoded to.
+func jsonType(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "boolean"
+		case u.Info()&types.IsNumeric != 0:
+			return "number"
+		case u.Info()&types.IsString != 0:
+			return "string"
+		}
+	case *types.Pointer:
+		return jsonType(u.Elem())
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "string"
+		}
+		return "array"
+	case *types.Array:
+		return "array"
+	case *types.Struct, *types.Map:
+		if isNamed(t, "time", "Time") {
+			return "string"
+		}
+		return "object"
+	}
+	return ""
+}
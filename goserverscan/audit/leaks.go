@@ -0,0 +1,305 @@
+package audit
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// LeaksAnalyzer reports sensitive data written to logs, whole request
+// dumps and internal errors reflected to clients.
+var LeaksAnalyzer = &analysis.Analyzer{
+	Name:             "leaks",
+	Doc:              "report sensitive data logging and error detail leakage",
+	Run:              runLeaks,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var (
+	checkLogSensitive = RegisterCheck(&Check{
+		ID:         "log-sensitive-data",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-532",
+	})
+	checkLogRequestDump = RegisterCheck(&Check{
+		ID:         "log-request-dump",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-532",
+	})
+	checkErrorDetail = RegisterCheck(&Check{
+		ID:         "error-detail-leak",
+		Severity:   SeverityLow,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-209",
+	})
+)
+
+// sensitiveNameRE matches the names of variables, fields and log keys
+// holding secrets.
+var sensitiveNameRE = regexp.MustCompile(`(?i)passw(or)?d|secret|token|authorization|api_?key|credential|private_?key`)
+
+var logPackages = []string{
+	"log",
+	"log/slog",
+	"go.uber.org/zap",
+	"github.com/sirupsen/logrus",
+}
+
+// logTypes are the logger types of logPackages.
+var logTypes = map[string][]string{
+	"log":                        {"Logger"},
+	"log/slog":                   {"Logger"},
+	"go.uber.org/zap":            {"Logger", "SugaredLogger"},
+	"github.com/sirupsen/logrus": {"Logger", "Entry"},
+}
+
+var logFuncs = map[string]bool{
+	"Print": true, "Printf": true, "Println": true,
+	"Fatal": true, "Fatalf": true, "Fatalln": true, "Fatalw": true,
+	"Panic": true, "Panicf": true, "Panicln": true, "Panicw": true, "DPanic": true,
+	"Trace": true, "Tracef": true,
+	"Debug": true, "Debugf": true, "Debugw": true, "DebugContext": true,
+	"Info": true, "Infof": true, "Infow": true, "InfoContext": true,
+	"Warn": true, "Warnf": true, "Warnw": true, "WarnContext": true,
+	"Warning": true, "Warningf": true,
+	"Error": true, "Errorf": true, "Errorw": true, "ErrorContext": true,
+	"Log": true, "Logf": true, "LogAttrs": true,
+	"With": true, "WithField": true, "WithFields": true,
+}
+
+func runLeaks(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch {
+			case isPkgSelector(pass.TypesInfo, file, sel, "net/http/httputil", "DumpRequest"):
+				// DumpRequestOut is left out as it dumps outgoing
+				// requests of clients.
+				report(pass, call, checkLogRequestDump, "whole request dumped%s, including credentials in headers and body", onRoutes(routesReaching(pass, routes, file, call)))
+			case isLogCall(pass, file, sel):
+				checkLogArgs(pass, routes, file, call)
+			default:
+				checkErrorResponse(pass, file, sel, call)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// isLogCall reports whether sel is a logging function of logPackages
+// or a logging method of one of their loggers.
+func isLogCall(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr) bool {
+	if !logFuncs[sel.Sel.Name] {
+		return false
+	}
+	for _, path := range logPackages {
+		if isPkgSelector(pass.TypesInfo, file, sel, path, sel.Sel.Name) {
+			return true
+		}
+	}
+
+	t := typeOf(pass.TypesInfo, sel.X)
+	if t == nil {
+		// Without type information, guess from the receiver name.
+		name := strings.ToLower(extractIdent(rootExpr(sel.X)))
+		return strings.Contains(name, "log") && importsAnyPath(file, logPackages)
+	}
+	for path, names := range logTypes {
+		for _, name := range names {
+			if isNamed(t, path, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkLogArgs(pass *analysis.Pass, routes *Routes, file *ast.File, call *ast.CallExpr) {
+	// Key value pairs, as in slog.Info("msg", "token", token), are
+	// reported once.
+	var sensitive bool
+	for i, arg := range call.Args {
+		if t := typeOf(pass.TypesInfo, arg); t != nil && (isNamed(t, "net/http", "Request") || isNamed(t, "net/http", "Header")) {
+			report(pass, arg, checkLogRequestDump, "whole request logged%s, including credentials in headers", onRoutes(routesReaching(pass, routes, file, call)))
+			continue
+		}
+		// The message, or format, names what is logged.
+		if _, ok := arg.(*ast.BasicLit); ok && i == 0 {
+			continue
+		}
+		if name := sensitiveName(pass.TypesInfo, arg); name != "" && !sensitive {
+			sensitive = true
+			report(pass, arg, checkLogSensitive, "%s written to logs", name)
+		}
+	}
+}
+
+// sensitiveName returns the first name in expr, identifier, field or
+// constant key such as zap.String("token", t), matching
+// sensitiveNameRE.
+func sensitiveName(info *types.Info, expr ast.Expr) (name string) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if name != "" {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			// len(password) or hash(token) do not leak their argument.
+			if fn := extractIdent(node.Fun); fn == "len" || strings.Contains(strings.ToLower(fn), "hash") || strings.Contains(strings.ToLower(fn), "mask") {
+				return false
+			}
+		case *ast.Ident:
+			if sensitiveNameRE.MatchString(node.Name) && !isBoolOrNumber(info, node) && isValue(info, node) {
+				name = node.Name
+			}
+		case *ast.SelectorExpr:
+			if sensitiveNameRE.MatchString(node.Sel.Name) && !isBoolOrNumber(info, node) && isValue(info, node.Sel) {
+				name = node.Sel.Name
+			}
+			// Only the field name matters, r.Token but not token.Value.
+			ast.Inspect(node.X, func(n ast.Node) bool {
+				if c, ok := n.(*ast.CallExpr); ok && name == "" {
+					name = sensitiveName(info, c)
+				}
+				return name == ""
+			})
+			return false
+		case *ast.BasicLit:
+			if s, err := strconv.Unquote(node.Value); err == nil && sensitiveNameRE.MatchString(s) && len(s) < 32 && !strings.ContainsAny(s, " %") {
+				name = s
+			}
+		}
+		return name == ""
+	})
+	return name
+}
+
+// isValue reports whether ident designates a value rather than a
+// package, type or function.
+func isValue(info *types.Info, ident *ast.Ident) bool {
+	switch info.Uses[ident].(type) {
+	case *types.PkgName, *types.TypeName, *types.Func:
+		return false
+	}
+	return true
+}
+
+func isBoolOrNumber(info *types.Info, expr ast.Expr) bool {
+	t := typeOf(info, expr)
+	if t == nil {
+		return false
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsBoolean|types.IsNumeric) != 0
+}
+
+// checkErrorResponse reports internal errors written to clients with
+// http.Error, fmt.Fprint(w, err) or w.Write([]byte(err.Error())).
+func checkErrorResponse(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr, call *ast.CallExpr) {
+	var msgs []ast.Expr
+	switch {
+	case isPkgSelector(pass.TypesInfo, file, sel, "net/http", "Error"):
+		if len(call.Args) != 3 || isClientErrorCode(pass.TypesInfo, call.Args[2]) {
+			return
+		}
+		msgs = call.Args[1:2]
+	case isPkgSelector(pass.TypesInfo, file, sel, "fmt", "Fprint"),
+		isPkgSelector(pass.TypesInfo, file, sel, "fmt", "Fprintf"),
+		isPkgSelector(pass.TypesInfo, file, sel, "fmt", "Fprintln"),
+		isPkgSelector(pass.TypesInfo, file, sel, "io", "WriteString"):
+		if len(call.Args) < 2 || !isResponseWriter(pass.TypesInfo, call.Args[0]) {
+			return
+		}
+		msgs = call.Args[1:]
+	case sel.Sel.Name == "Write" && len(call.Args) == 1 && isResponseWriter(pass.TypesInfo, sel.X):
+		msgs = call.Args
+	default:
+		return
+	}
+
+	for _, msg := range msgs {
+		if containsError(pass.TypesInfo, msg) {
+			report(pass, msg, checkErrorDetail, "internal error details written to the HTTP response")
+			return
+		}
+	}
+}
+
+// isClientErrorCode reports whether code is a constant 4xx status, for
+// which error messages are usually meant for clients.
+func isClientErrorCode(info *types.Info, code ast.Expr) bool {
+	tv, ok := info.Types[code]
+	if ok && tv.Value != nil {
+		if v, exact := constant.Int64Val(tv.Value); exact {
+			return v >= 400 && v < 500
+		}
+	}
+	if sel, ok := code.(*ast.SelectorExpr); ok {
+		return clientErrorStatus[sel.Sel.Name]
+	}
+	return false
+}
+
+// clientErrorStatus are the common 4xx net/http status constants, for
+// code whose dependencies are missing.
+var clientErrorStatus = map[string]bool{
+	"StatusBadRequest":            true,
+	"StatusUnauthorized":          true,
+	"StatusForbidden":             true,
+	"StatusNotFound":              true,
+	"StatusMethodNotAllowed":      true,
+	"StatusConflict":              true,
+	"StatusRequestEntityTooLarge": true,
+	"StatusUnsupportedMediaType":  true,
+	"StatusUnprocessableEntity":   true,
+	"StatusTooManyRequests":       true,
+}
+
+func isResponseWriter(info *types.Info, expr ast.Expr) bool {
+	if t := typeOf(info, expr); t != nil {
+		return isNamed(t, "net/http", "ResponseWriter")
+	}
+	return extractIdent(expr) == "w"
+}
+
+// containsError reports whether expr holds an error value or its
+// message, as err.Error() or fmt.Sprintf("failed: %v", err).
+func containsError(info *types.Info, expr ast.Expr) (found bool) {
+	errorType := types.Universe.Lookup("error").Type()
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Error" && len(node.Args) == 0 {
+				if t := typeOf(info, sel.X); t == nil || types.Implements(t, errorType.Underlying().(*types.Interface)) {
+					found = true
+				}
+			}
+		case *ast.Ident:
+			if t := typeOf(info, node); t != nil && isValue(info, node) && types.Identical(t, errorType) {
+				found = true
+			} else if t == nil && node.Name == "err" {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+)
+
+type Snippet struct {
+	Code     string
+	Filename string
+	Line     int
+}
+
+func (s Snippet) String() string {
+	return fmt.Sprintf("%s:%d '%s'", s.Filename, s.Line, s.Code)
+}
+
+func NewSnippet(fset *token.FileSet, n ast.Node) Snippet {
+	var b bytes.Buffer
+	printer.Fprint(&b, fset, n)
+	pos := fset.Position(n.Pos())
+	return Snippet{
+		Code:     b.String(),
+		Filename: pos.Filename,
+		Line:     pos.Line,
+	}
+}
+
+// Router groups the routes served together. Besides HTTP routers, gRPC
+// servers list their RPCs as routes, with interceptors as middleware,
+// and GraphQL servers their resolvers, with the path they are mounted
+// on as Mount.
+type Router struct {
+	Snippet
+	Kind       string
+	Mount      string
+	Middleware []string
+	Routes     []*Route
+}
+
+// Route is a route registration. HandlerLocation is the file:line
+// where its handler is defined, when it is in the scanned package, and
+// Inputs what the handler reads from requests.
+type Route struct {
+	Snippet
+	Path            string
+	Methods         []string
+	Middleware      []string
+	Handler         string
+	HandlerLocation string
+	Inputs          []*Input
+	Authenticated   bool
+	WebSocket       bool
+}
+
+// Where inputs are read from requests.
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InForm   = "form"
+	InHeader = "header"
+	InCookie = "cookie"
+	InBody   = "body"
+)
+
+// Input is a value read from requests. Body inputs are the fields of
+// the JSON document decoded, with Type the JSON type of their value.
+type Input struct {
+	In   string
+	Name string
+	Type string
+}
+
+func (in *Input) String() string { return in.In + ":" + in.Name }
+
+type OutGoingCall struct {
+	Snippet
+	Kind string
+}
+
+func (c *OutGoingCall) String() string {
+	return fmt.Sprintf("http call at line %s:%d (%s)", c.Filename, c.Line, c.Kind)
+}
+
+// Handler is a type implementing http.Handler, or a function returning
+// one, with the paths of the routes and the addresses of the servers
+// mounting it.
+type Handler struct {
+	Snippet
+	Name    string
+	Kind    string
+	Routes  []string
+	Servers []string
+}
+
+// ConfigKey is a configuration value read by the program: an
+// environment variable, a flag, a viper key or a configuration file.
+// Flows lists the security relevant places, such as listen addresses
+// or TLS settings, the value reaches.
+type ConfigKey struct {
+	Snippet
+	Source  string
+	Name    string
+	Default string
+	Flows   []string
+}
+
+type Finding struct {
+	Snippet
+	RuleID     string
+	Severity   Severity
+	Confidence Confidence
+	Message    string
+	CWE        string
+}
+
+func (f Finding) String() string {
+	if f.CWE != "" {
+		return fmt.Sprintf("[%s/%s] %s: %s (%s) at %s", f.Severity, f.Confidence, f.RuleID, f.Message, f.CWE, f.Snippet)
+	}
+	return fmt.Sprintf("[%s/%s] %s: %s at %s", f.Severity, f.Confidence, f.RuleID, f.Message, f.Snippet)
+}
+
+// StaticMount is a handler serving files under Prefix from Root, a
+// directory or, when Embed is set, embedded files. Files lists the
+// filesystem paths reachable over HTTP, relative to the package
+// directory, when Root could be resolved from the scanned sources.
+type StaticMount struct {
+	Snippet
+	Kind    string
+	Prefix  string
+	Root    string
+	Embed   bool
+	Listing bool
+	Files   []string
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"go/ast"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// OutgoingCallsAnalyzer collects the HTTP requests sent by the code.
+var OutgoingCallsAnalyzer = &analysis.Analyzer{
+	Name:             "outgoingcalls",
+	Doc:              "collect outgoing HTTP calls",
+	Run:              runOutgoingCalls,
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf([]*OutGoingCall(nil)),
+}
+
+func runOutgoingCalls(pass *analysis.Pass) (interface{}, error) {
+	var calls []*OutGoingCall
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if kind := detectHTTPCalls(pass, file, sel); kind != "" {
+					calls = append(calls, &OutGoingCall{
+						Snippet: NewSnippet(pass.Fset, sel),
+						Kind:    kind,
+					})
+				}
+			}
+			return true
+		})
+	}
+	return calls, nil
+}
+
+func detectHTTPCalls(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr) string {
+	for _, name := range []string{"Get", "Head", "Post", "PostForm"} {
+		if isPkgSelector(pass.TypesInfo, file, sel, "net/http", name) {
+			return "http." + name
+		}
+	}
+	return ""
+}
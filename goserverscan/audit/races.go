@@ -0,0 +1,309 @@
+package audit
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// RacesAnalyzer reports state shared between requests, package
+// variables, handler receiver fields and variables captured by handler
+// closures, written from handlers without holding a lock.
+var RacesAnalyzer = &analysis.Analyzer{
+	Name:             "races",
+	Doc:              "report handler-shared state written without synchronization",
+	Run:              runRaces,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var checkSharedStateRace = RegisterCheck(&Check{
+	ID:         "handler-shared-state-race",
+	Severity:   SeverityMedium,
+	Confidence: ConfidenceLow,
+	CWE:        "CWE-362",
+})
+
+// handlerBody is the code run for each request by a handler, and the
+// type of the function declaring its parameters. Fields of recv, and
+// variables declared outside of closure, are shared between requests.
+type handlerBody struct {
+	body    *ast.BlockStmt
+	params  *ast.FuncType
+	recv    string
+	closure *ast.FuncLit
+}
+
+// sharedWrite is a write to shared state and the routes running it.
+type sharedWrite struct {
+	node   ast.Node
+	target string
+	routes []*Route
+}
+
+func runRaces(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	writes := make(map[ast.Node]*sharedWrite)
+	for _, router := range routes.Routers {
+		for _, route := range router.Routes {
+			handler, ok := routes.handlers[route]
+			if !ok {
+				continue
+			}
+			for _, h := range handlerBodies(pass, handler) {
+				for _, w := range h.unsyncedWrites(pass) {
+					if existing, ok := writes[w.node]; ok {
+						w = existing
+					} else {
+						writes[w.node] = w
+					}
+					w.routes = append(w.routes, route)
+				}
+			}
+		}
+	}
+
+	sorted := make([]*sharedWrite, 0, len(writes))
+	for _, w := range writes {
+		sorted = append(sorted, w)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].node.Pos() < sorted[j].node.Pos() })
+	for _, w := range sorted {
+		report(pass, w.node, checkSharedStateRace, "%s shared between requests is written by handler%s without synchronization", w.target, onRoutes(w.routes))
+	}
+	return nil, nil
+}
+
+// handlerBodies resolves the handler expression of a route: function
+// literals, functions and methods of the package, and handler
+// factories such as s.handleIndex() returning closures.
+func handlerBodies(pass *analysis.Pass, handler ast.Expr) []handlerBody {
+	switch h := handler.(type) {
+	case *ast.FuncLit:
+		return []handlerBody{{body: h.Body, params: h.Type, closure: h}}
+	case *ast.Ident, *ast.SelectorExpr:
+		if decl := resolveFuncDecl(pass, h); decl != nil && decl.Body != nil {
+			return []handlerBody{{body: decl.Body, params: decl.Type, recv: receiverName(decl)}}
+		}
+	case *ast.CallExpr:
+		decl := resolveFuncDecl(pass, h.Fun)
+		if decl == nil || decl.Body == nil {
+			return nil
+		}
+		var bodies []handlerBody
+		ast.Inspect(decl.Body, func(n ast.Node) bool {
+			ret, ok := n.(*ast.ReturnStmt)
+			if !ok {
+				return true
+			}
+			for _, res := range ret.Results {
+				// return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {...})
+				if call, ok := res.(*ast.CallExpr); ok && len(call.Args) == 1 {
+					res = call.Args[0]
+				}
+				if lit, ok := res.(*ast.FuncLit); ok {
+					bodies = append(bodies, handlerBody{body: lit.Body, params: lit.Type, recv: receiverName(decl), closure: lit})
+				}
+			}
+			return true
+		})
+		return bodies
+	}
+	return nil
+}
+
+// resolveFuncDecl returns the declaration of the function or method
+// designated by expr, by name when type information is missing.
+func resolveFuncDecl(pass *analysis.Pass, expr ast.Expr) *ast.FuncDecl {
+	var ident *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return nil
+	}
+	if obj := pass.TypesInfo.Uses[ident]; obj != nil {
+		return funcDecl(pass, obj)
+	}
+	_, isMethod := expr.(*ast.SelectorExpr)
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == ident.Name && (fn.Recv != nil) == isMethod {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+func receiverName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 || len(decl.Recv.List[0].Names) == 0 {
+		return ""
+	}
+	return decl.Recv.List[0].Names[0].Name
+}
+
+// unsyncedWrites returns the writes to shared state in the handler
+// made while no lock is held: assignments, increments and deletes.
+// Writes in sync.Once.Do functions are ignored.
+func (h handlerBody) unsyncedWrites(pass *analysis.Pass) (writes []*sharedWrite) {
+	check := func(node ast.Node, target ast.Expr) {
+		if name, ok := h.sharedTarget(pass, target); ok && !lockedAt(h.body, node.Pos()) {
+			writes = append(writes, &sharedWrite{node: node, target: name})
+		}
+	}
+	ast.Inspect(h.body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE {
+				for _, lhs := range node.Lhs {
+					check(node, lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			check(node, node.X)
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Do" {
+				if t := typeOf(pass.TypesInfo, sel.X); t != nil && isNamed(t, "sync", "Once") {
+					return false
+				}
+			}
+			if extractIdent(node.Fun) == "delete" && len(node.Args) == 2 {
+				check(node, node.Args[0])
+			}
+		}
+		return true
+	})
+	return writes
+}
+
+// sharedTarget reports whether the variable written in expr, such as
+// cache in cache[key] or s.hits in s.hits++, is shared between
+// requests and returns its name.
+func (h handlerBody) sharedTarget(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	var field string
+	for {
+		switch e := expr.(type) {
+		case *ast.IndexExpr:
+			expr = e.X
+			continue
+		case *ast.ParenExpr:
+			expr = e.X
+			continue
+		case *ast.StarExpr:
+			expr = e.X
+			continue
+		case *ast.SelectorExpr:
+			field = e.Sel.Name
+			expr = e.X
+			continue
+		}
+		break
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok || ident.Name == "_" {
+		return "", false
+	}
+	name := ident.Name
+	if field != "" {
+		name += "." + field
+	}
+
+	obj, _ := pass.TypesInfo.Uses[ident].(*types.Var)
+	if obj != nil && isSyncType(obj.Type()) {
+		return "", false
+	}
+	switch {
+	case h.recv != "" && ident.Name == h.recv && field != "":
+		return "field " + name, true
+	case obj == nil || obj.Pkg() == nil:
+		return "", false
+	case obj.Parent() == obj.Pkg().Scope():
+		return "package variable " + name, true
+	case h.closure != nil && obj.Parent() != nil && !(obj.Pos() >= h.closure.Pos() && obj.Pos() < h.closure.End()):
+		return "captured variable " + name, true
+	}
+	return "", false
+}
+
+func isSyncType(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == "sync" || path == "sync/atomic"
+}
+
+// lockedAt reports whether a lock is held in body at pos: Lock was
+// called on a mutex by a statement run before pos, in the blocks
+// enclosing pos, and Unlock was not called on it since. Deferred
+// calls are not run before pos.
+func lockedAt(body *ast.BlockStmt, pos token.Pos) bool {
+	held := make(map[string]bool)
+	var walk func(stmts []ast.Stmt)
+	walk = func(stmts []ast.Stmt) {
+		for _, stmt := range stmts {
+			if stmt.Pos() > pos {
+				return
+			}
+			if pos < stmt.End() {
+				innerStmts(stmt, pos, walk)
+				return
+			}
+			expr, ok := stmt.(*ast.ExprStmt)
+			if !ok {
+				continue
+			}
+			call, ok := expr.X.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				switch sel.Sel.Name {
+				case "Lock":
+					held[exprValue(sel.X)] = true
+				case "Unlock":
+					delete(held, exprValue(sel.X))
+				}
+			}
+		}
+	}
+	walk(body.List)
+	return len(held) > 0
+}
+
+// innerStmts calls walk with the statements of the outermost block,
+// case or function literal of stmt enclosing pos, if any.
+func innerStmts(stmt ast.Stmt, pos token.Pos, walk func([]ast.Stmt)) {
+	done := false
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if done || n == nil || n.Pos() > pos || pos >= n.End() {
+			return false
+		}
+		if n == stmt {
+			return true
+		}
+		switch b := n.(type) {
+		case *ast.BlockStmt:
+			walk(b.List)
+		case *ast.CaseClause:
+			walk(b.Body)
+		case *ast.CommClause:
+			walk(b.Body)
+		default:
+			return true
+		}
+		done = true
+		return false
+	})
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// RateLimitAnalyzer reports the authentication endpoints, routes named
+// after logins, tokens, password resets or one-time passwords and
+// handlers comparing bcrypt hashes, that no rate limiter protects
+// against brute force: neither a middleware of the route, its router
+// or the whole server, nor the handler itself.
+var RateLimitAnalyzer = &analysis.Analyzer{
+	Name:             "ratelimit",
+	Doc:              "report authentication endpoints lacking rate limiting",
+	Run:              runRateLimit,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var checkAuthNoRateLimit = RegisterCheck(&Check{
+	ID:         "auth-no-rate-limit",
+	Severity:   SeverityMedium,
+	Confidence: ConfidenceMedium,
+	CWE:        "CWE-307",
+})
+
+var rateLimitMiddlewareFlag string
+
+func init() {
+	RateLimitAnalyzer.Flags.StringVar(&rateLimitMiddlewareFlag, "middleware", "", "Comma separated names of functions rate limiting handlers")
+}
+
+var (
+	// authEndpointRE matches the paths and handler names of
+	// authentication endpoints.
+	authEndpointRE = regexp.MustCompile(`(?i)log_?in|sign_?in|token|reset|otp`)
+
+	// rateLimitNameRE matches the middleware of tollbooth and httprate,
+	// and functions named after rate limiting.
+	rateLimitNameRE = regexp.MustCompile(`(?i)rate_?limit|throttl|tollbooth|httprate|limiter`)
+)
+
+var rateLimitPaths = []string{
+	"golang.org/x/time/rate",
+	"github.com/didip/tollbooth",
+	"github.com/didip/tollbooth/v7",
+	"github.com/go-chi/httprate",
+}
+
+func runRateLimit(pass *analysis.Pass) (interface{}, error) {
+	isRateLimiter := func(mw string) bool { return isRateLimitMiddleware(pass, mw) }
+	if serverProtected(pass, isRateLimiter) {
+		return nil, nil
+	}
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+	for _, router := range routes.Routers {
+		if anyMiddleware(router.Middleware, isRateLimiter) {
+			continue
+		}
+		for _, route := range router.Routes {
+			handler, ok := routes.handlers[route]
+			if !ok || anyMiddleware(route.Middleware, isRateLimiter) {
+				continue
+			}
+			bodies := handlerBodies(pass, handler)
+			if !authEndpointRE.MatchString(route.Path) && !authEndpointRE.MatchString(route.Handler) && !anyBody(bodies, comparesPasswords) {
+				continue
+			}
+			if anyBody(bodies, func(body *ast.BlockStmt) bool { return usesRateLimiter(pass, body) }) {
+				continue
+			}
+			on := strings.TrimSpace(strings.Join(route.Methods, ",") + " " + route.Path)
+			report(pass, handler, checkAuthNoRateLimit, "%s authenticates users without rate limiting, allowing brute force", on)
+		}
+	}
+	return nil, nil
+}
+
+func anyMiddleware(middleware []string, f func(string) bool) bool {
+	for _, mw := range middleware {
+		if f(mw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRateLimitMiddleware reports whether the middleware mw, as rendered
+// in routes, limits the rate of requests: it is named after rate
+// limiting or configured, or is a function of the package using a rate
+// limiter.
+func isRateLimitMiddleware(pass *analysis.Pass, mw string) bool {
+	if i := strings.Index(mw, "("); i >= 0 {
+		mw = mw[:i]
+	}
+	if rateLimitNameRE.MatchString(mw) {
+		return true
+	}
+	name := mw[strings.LastIndex(mw, ".")+1:]
+	for _, configured := range strings.Split(rateLimitMiddlewareFlag, ",") {
+		if configured = strings.TrimSpace(configured); configured != "" && configured == name {
+			return true
+		}
+	}
+	if fn, ok := pass.Pkg.Scope().Lookup(name).(*types.Func); ok {
+		if decl := funcDecl(pass, fn); decl != nil && decl.Body != nil {
+			return usesRateLimiter(pass, decl.Body)
+		}
+	}
+	return false
+}
+
+// usesRateLimiter reports whether body calls a rate limiting package,
+// or takes tokens from an x/time/rate Limiter.
+func usesRateLimiter(pass *analysis.Pass, body *ast.BlockStmt) (found bool) {
+	file := fileOf(pass, body.Pos())
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return !found
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			switch sel.Sel.Name {
+			case "Allow", "AllowN", "Wait", "WaitN", "Reserve", "ReserveN":
+				if t := typeOf(pass.TypesInfo, sel.X); t != nil && isNamed(t, "golang.org/x/time/rate", "Limiter") {
+					found = true
+				}
+			}
+			if isAnyPkgSelector(pass, file, sel, rateLimitPaths, sel.Sel.Name) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// comparesPasswords reports whether body checks a password against a
+// bcrypt hash.
+func comparesPasswords(body *ast.BlockStmt) (found bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && isSelectorExpr(sel, "bcrypt", "CompareHashAndPassword") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
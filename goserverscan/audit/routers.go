@@ -0,0 +1,679 @@
+package audit
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// RegistersRoutesFact marks a function registering routes on some of
+// its router parameters, indexed by position, so that the routes are
+// merged on the routers callers pass.
+type RegistersRoutesFact struct {
+	Params map[int]*Router
+}
+
+func (*RegistersRoutesFact) AFact() {}
+
+func (*RegistersRoutesFact) String() string { return "registersRoutes" }
+
+// routerTracker follows router instances through local and package
+// variables, struct fields, return values and parameters, and through
+// the facts of imported packages, so that the routes registered on
+// any expression designating an instance end up on a single Router.
+type routerTracker struct {
+	pass       *analysis.Pass
+	result     *Routes
+	collectors map[*ast.File]*routesCollector
+	decls      map[types.Object]*ast.FuncDecl
+
+	routers []*Router
+	// objs maps variables, fields and functions returning a router to
+	// the instances they designate.
+	objs  map[types.Object][]*Router
+	sites map[*ast.CallExpr]*Router
+	// elements are the variables standing for the elements of maps and
+	// slices at constant indexes, as routers["api"], and containers
+	// the map or slice of each.
+	elements   map[element]*types.Var
+	containers map[types.Object]types.Object
+	// params holds the routers of router parameters, and callers the
+	// routers passed to them within the package.
+	params  map[types.Object]*Router
+	callers map[*Router][]*Router
+
+	routerMiddleware map[*Router][]ast.Expr
+	routeMiddleware  map[*Route][]ast.Expr
+}
+
+func newRouterTracker(pass *analysis.Pass, res *Routes) *routerTracker {
+	t := &routerTracker{
+		pass:             pass,
+		result:           res,
+		collectors:       make(map[*ast.File]*routesCollector),
+		decls:            make(map[types.Object]*ast.FuncDecl),
+		objs:             make(map[types.Object][]*Router),
+		elements:         make(map[element]*types.Var),
+		containers:       make(map[types.Object]types.Object),
+		sites:            make(map[*ast.CallExpr]*Router),
+		params:           make(map[types.Object]*Router),
+		callers:          make(map[*Router][]*Router),
+		routerMiddleware: make(map[*Router][]ast.Expr),
+		routeMiddleware:  make(map[*Route][]ast.Expr),
+	}
+	for _, file := range pass.Files {
+		t.collectors[file] = &routesCollector{pass: pass, file: file, result: res}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && pass.TypesInfo.Defs[fn.Name] != nil {
+				t.decls[pass.TypesInfo.Defs[fn.Name]] = fn
+			}
+		}
+	}
+	return t
+}
+
+// Routers returns the routers found, in the order they are declared,
+// leaving out the parameters merged on the routers of their callers.
+func (t *routerTracker) Routers() []*Router {
+	var routers []*Router
+	for _, r := range t.routers {
+		if len(t.callers[r]) == 0 {
+			routers = append(routers, r)
+		}
+	}
+	sort.SliceStable(routers, func(i, j int) bool {
+		return routers[i].Filename < routers[j].Filename ||
+			(routers[i].Filename == routers[j].Filename && routers[i].Line < routers[j].Line)
+	})
+	return routers
+}
+
+func (t *routerTracker) newRouter(snippet Snippet, kind string) *Router {
+	r := &Router{Snippet: snippet, Kind: kind}
+	t.routers = append(t.routers, r)
+	return r
+}
+
+// track binds variables, fields and functions to the routers they
+// designate until no more bindings are found.
+func (t *routerTracker) track() {
+	for _, file := range t.pass.Files {
+		c := t.collectors[file]
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			for _, field := range fn.Type.Params.List {
+				kind := c.routerType(field.Type)
+				if kind == "" {
+					continue
+				}
+				for _, name := range field.Names {
+					if obj := t.pass.TypesInfo.Defs[name]; obj != nil {
+						r := t.newRouter(NewSnippet(t.pass.Fset, fn.Name), kind)
+						t.params[obj] = r
+						t.objs[obj] = []*Router{r}
+					}
+				}
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, file := range t.pass.Files {
+			c := t.collectors[file]
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch node := n.(type) {
+				case *ast.AssignStmt:
+					if len(node.Lhs) == len(node.Rhs) {
+						for i := range node.Lhs {
+							changed = t.bind(c, node.Lhs[i], node.Rhs[i], node) || changed
+						}
+					} else if i := t.routerResult(c, node.Rhs[0]); i >= 0 && i < len(node.Lhs) {
+						// r, err := newRouter()
+						changed = t.bind(c, node.Lhs[i], node.Rhs[0], node) || changed
+					}
+				case *ast.ValueSpec:
+					for i, name := range node.Names {
+						if i < len(node.Values) {
+							changed = t.bind(c, name, node.Values[i], node) || changed
+						}
+					}
+					if len(node.Values) == 1 && len(node.Names) > 1 {
+						if i := t.routerResult(c, node.Values[0]); i >= 0 && i < len(node.Names) {
+							changed = t.bind(c, node.Names[i], node.Values[0], node) || changed
+						}
+					}
+				case *ast.KeyValueExpr:
+					// &server{router: mux.NewRouter()}
+					changed = t.bind(c, node.Key, node.Value, node) || changed
+				case *ast.FuncDecl:
+					changed = t.bindResults(c, node) || changed
+				}
+				return true
+			})
+		}
+	}
+}
+
+// bind binds lhs to the routers of rhs and reports whether new
+// bindings were made.
+func (t *routerTracker) bind(c *routesCollector, lhs, rhs ast.Expr, node ast.Node) bool {
+	routers := t.routersOf(c, rhs, node)
+	if len(routers) == 0 {
+		return false
+	}
+	obj := t.object(lhs)
+	if obj == nil {
+		return false
+	}
+	return t.add(obj, routers)
+}
+
+// bindResults binds fn to the routers it returns, as its only result
+// or as its only router result among others, as in (*mux.Router, error).
+func (t *routerTracker) bindResults(c *routesCollector, fn *ast.FuncDecl) (changed bool) {
+	obj := t.pass.TypesInfo.Defs[fn.Name]
+	if obj == nil || fn.Body == nil || fn.Type.Results == nil {
+		return false
+	}
+	index, _ := c.routerResult(fn.Type)
+	if index < 0 {
+		return false
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if len(node.Results) == fn.Type.Results.NumFields() {
+				changed = t.add(obj, t.routersOf(c, node.Results[index], node)) || changed
+			}
+		}
+		return true
+	})
+	return changed
+}
+
+// routerResult returns the index of the router among the results of
+// expr, a call returning several values, or -1.
+func (t *routerTracker) routerResult(c *routesCollector, expr ast.Expr) int {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return -1
+	}
+	if sig, ok := typeOf(t.pass.TypesInfo, call.Fun).(*types.Signature); ok {
+		index := -1
+		for i := 0; i < sig.Results().Len(); i++ {
+			if isRouterType(sig.Results().At(i).Type()) {
+				if index >= 0 {
+					return -1
+				}
+				index = i
+			}
+		}
+		return index
+	}
+	if decl := t.decls[t.object(call.Fun)]; decl != nil {
+		index, _ := c.routerResult(decl.Type)
+		return index
+	}
+	return -1
+}
+
+func isRouterType(t types.Type) bool {
+	return isNamed(t, "github.com/gorilla/mux", "Router") || isNamed(t, "net/http", "ServeMux")
+}
+
+func (t *routerTracker) add(obj types.Object, routers []*Router) (changed bool) {
+	for _, r := range routers {
+		if !containsRouter(t.objs[obj], r) {
+			t.objs[obj] = append(t.objs[obj], r)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// routersOf returns the routers designated by expr: a router created
+// or returned by a function, or a tracked variable or field.
+func (t *routerTracker) routersOf(c *routesCollector, expr ast.Expr, node ast.Node) []*Router {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return t.lookup(expr)
+	}
+	if r := t.sites[call]; r != nil {
+		return []*Router{r}
+	}
+	if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "append" {
+		// routers = append(routers, mux.NewRouter())
+		if _, ok := t.pass.TypesInfo.Uses[id].(*types.Builtin); ok {
+			var routers []*Router
+			for _, arg := range call.Args[1:] {
+				routers = append(routers, t.routersOf(c, arg, node)...)
+			}
+			return routers
+		}
+	}
+	if kind := c.routerConstructor(call); kind != "" {
+		if spec, ok := node.(*ast.ValueSpec); ok {
+			// Without its comments.
+			node = &ast.ValueSpec{Names: spec.Names, Type: spec.Type, Values: spec.Values}
+		}
+		r := t.newRouter(NewSnippet(t.pass.Fset, node), kind)
+		t.sites[call] = r
+		return []*Router{r}
+	}
+	obj := t.object(call.Fun)
+	if routers := t.objs[obj]; len(routers) > 0 {
+		return routers
+	}
+	var fact RouterFact
+	if fn, ok := obj.(*types.Func); ok && fn.Pkg() != t.pass.Pkg && t.pass.ImportObjectFact(fn, &fact) {
+		r := t.importRouter(&fact, NewSnippet(t.pass.Fset, node))
+		t.sites[call] = r
+		return []*Router{r}
+	}
+	return nil
+}
+
+// lookup returns the routers bound to the variable or field of expr,
+// or exported by the package it is imported from.
+func (t *routerTracker) lookup(expr ast.Expr) []*Router {
+	obj := t.object(expr)
+	if obj == nil {
+		return nil
+	}
+	if routers := t.objs[obj]; len(routers) > 0 {
+		return routers
+	}
+	if routers := t.elementRouters(obj); len(routers) > 0 {
+		return routers
+	}
+	var fact RouterFact
+	if v, ok := obj.(*types.Var); ok && v.Pkg() != t.pass.Pkg && t.pass.ImportObjectFact(v, &fact) {
+		r := t.importRouter(&fact, NewSnippet(t.pass.Fset, expr))
+		t.objs[obj] = []*Router{r}
+		return t.objs[obj]
+	}
+	return nil
+}
+
+// receivers returns the routers of expr, the receiver of a route
+// registration, creating one for router fields and variables whose
+// value is not tracked.
+func (t *routerTracker) receivers(expr ast.Expr) []*Router {
+	if routers := t.lookup(expr); len(routers) > 0 {
+		return routers
+	}
+	obj := t.object(expr)
+	kind, decl := t.declaredRouter(obj)
+	if kind == "" {
+		return nil
+	}
+	t.objs[obj] = []*Router{t.newRouter(NewSnippet(t.pass.Fset, decl), kind)}
+	return t.objs[obj]
+}
+
+// declaredRouter returns the kind of router of the type of obj, a
+// variable or field of the package, and its declaration.
+func (t *routerTracker) declaredRouter(obj types.Object) (string, ast.Node) {
+	v, ok := obj.(*types.Var)
+	if !ok || v.Pkg() != t.pass.Pkg {
+		return "", nil
+	}
+	for _, file := range t.pass.Files {
+		if v.Pos() < file.FileStart || v.Pos() > file.FileEnd {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(file, v.Pos(), v.Pos())
+		for _, n := range path {
+			switch decl := n.(type) {
+			case *ast.Field:
+				return t.collectors[file].routerType(decl.Type), decl
+			case *ast.ValueSpec:
+				if decl.Type != nil {
+					return t.collectors[file].routerType(decl.Type), decl
+				}
+				return "", nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// object returns the variable, field or function designated by expr.
+func (t *routerTracker) object(expr ast.Expr) types.Object {
+	info := t.pass.TypesInfo
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		if obj := info.Uses[e]; obj != nil {
+			return obj
+		}
+		return info.Defs[e]
+	case *ast.SelectorExpr:
+		if sel := info.Selections[e]; sel != nil {
+			return sel.Obj()
+		}
+		return info.Uses[e.Sel]
+	case *ast.IndexExpr:
+		return t.element(e)
+	}
+	return nil
+}
+
+type element struct {
+	container types.Object
+	index     string
+}
+
+// element returns the variable standing for the element of a map or
+// slice at a constant index, or the map or slice itself.
+func (t *routerTracker) element(e *ast.IndexExpr) types.Object {
+	container := t.object(e.X)
+	if container == nil {
+		return nil
+	}
+	tv, ok := t.pass.TypesInfo.Types[e.Index]
+	if !ok || tv.Value == nil {
+		return container
+	}
+	k := element{container, tv.Value.ExactString()}
+	v, ok := t.elements[k]
+	if !ok {
+		v = types.NewVar(token.NoPos, t.pass.Pkg, exprValue(e), nil)
+		t.elements[k] = v
+		t.containers[v] = container
+	}
+	return v
+}
+
+// elementRouters returns the routers of the map or slice containing
+// obj, when obj is an element, or of all the elements of obj.
+func (t *routerTracker) elementRouters(obj types.Object) (routers []*Router) {
+	if container := t.containers[obj]; container != nil {
+		return t.objs[container]
+	}
+	for k, v := range t.elements {
+		if k.container == obj {
+			for _, r := range t.objs[v] {
+				if !containsRouter(routers, r) {
+					routers = append(routers, r)
+				}
+			}
+		}
+	}
+	return routers
+}
+
+// importRouter returns a router for the instance described by fact,
+// with the routes registered in the package exporting it.
+func (t *routerTracker) importRouter(fact *RouterFact, at Snippet) *Router {
+	r := t.newRouter(at, fact.Kind)
+	if fact.Router != nil {
+		r.Snippet = fact.Router.Snippet
+		mergeRouter(r, fact.Router)
+	}
+	return r
+}
+
+// register collects the routes and middleware registered on tracked
+// routers, and links the routers passed as arguments to the router
+// parameters of the function called.
+func (t *routerTracker) register() {
+	registrations := make(map[*ast.CallExpr]*Route)
+	for _, file := range t.pass.Files {
+		c := t.collectors[file]
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			t.passRouters(call)
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch sel.Sel.Name {
+			case "Handle", "HandleFunc":
+				if len(call.Args) < 2 {
+					return true
+				}
+				routers := t.receivers(sel.X)
+				if len(routers) == 0 {
+					return true
+				}
+				route := &Route{
+					Snippet: NewSnippet(t.pass.Fset, call),
+					Path:    exprValue(call.Args[0]),
+				}
+				if method, path, ok := splitPattern(route.Path); ok {
+					route.Methods = []string{method}
+					route.Path = path
+				}
+				middleware, handler := c.unwrapHandler(call.Args[1])
+				for _, mw := range middleware {
+					route.Middleware = append(route.Middleware, exprValue(mw))
+				}
+				route.Handler = exprValue(handler)
+				t.result.handlers[route] = handler
+				t.routeMiddleware[route] = middleware
+				for _, r := range routers {
+					r.Routes = append(r.Routes, route)
+				}
+				registrations[call] = route
+			case "Use":
+				for _, r := range t.receivers(sel.X) {
+					for _, arg := range call.Args {
+						r.Middleware = append(r.Middleware, exprValue(arg))
+						t.routerMiddleware[r] = append(t.routerMiddleware[r], arg)
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	// gorilla/mux restricts methods with r.HandleFunc(...).Methods("POST")
+	for _, file := range t.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Methods" {
+				if inner, ok := sel.X.(*ast.CallExpr); ok && registrations[inner] != nil {
+					for _, arg := range call.Args {
+						registrations[inner].Methods = append(registrations[inner].Methods, exprValue(arg))
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	t.mergeParams()
+	t.authenticate()
+}
+
+// passRouters links the routers passed to call to the router
+// parameters of the function called when it is declared in the
+// package, or merges the routes it registers according to its facts.
+func (t *routerTracker) passRouters(call *ast.CallExpr) {
+	fn, ok := t.object(call.Fun).(*types.Func)
+	if !ok {
+		return
+	}
+	if decl := t.decls[fn]; decl != nil {
+		for i, name := range paramNames(decl) {
+			param := t.params[t.pass.TypesInfo.Defs[name]]
+			if param == nil || i >= len(call.Args) {
+				continue
+			}
+			for _, r := range t.receivers(call.Args[i]) {
+				if r != param && !containsRouter(t.callers[param], r) {
+					t.callers[param] = append(t.callers[param], r)
+				}
+			}
+		}
+		return
+	}
+	var fact RegistersRoutesFact
+	if fn.Pkg() == t.pass.Pkg || !t.pass.ImportObjectFact(fn, &fact) {
+		return
+	}
+	for i, param := range fact.Params {
+		if i < len(call.Args) {
+			for _, r := range t.receivers(call.Args[i]) {
+				mergeRouter(r, param)
+			}
+		}
+	}
+}
+
+// mergeParams adds the routes and middleware registered on router
+// parameters to the routers passed by callers, following parameters
+// passed on to other functions.
+func (t *routerTracker) mergeParams() {
+	for changed := true; changed; {
+		changed = false
+		for _, param := range t.routers {
+			for _, r := range t.callers[param] {
+				for _, route := range param.Routes {
+					if !containsRoute(r.Routes, route) {
+						r.Routes = append(r.Routes, route)
+						changed = true
+					}
+				}
+				for _, mw := range t.routerMiddleware[param] {
+					if !containsExpr(t.routerMiddleware[r], mw) {
+						r.Middleware = append(r.Middleware, exprValue(mw))
+						t.routerMiddleware[r] = append(t.routerMiddleware[r], mw)
+						changed = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// authenticate marks the routes wrapped, directly or through their
+// router, by an authentication middleware.
+func (t *routerTracker) authenticate() {
+	for _, r := range t.routers {
+		for _, route := range r.Routes {
+			if anyAuthMiddleware(t.pass, t.routerMiddleware[r]) || anyAuthMiddleware(t.pass, t.routeMiddleware[route]) {
+				route.Authenticated = true
+			}
+		}
+	}
+}
+
+// exportFacts exports a RouterFact for the package variables and
+// functions designating a router, and a RegistersRoutesFact for the
+// functions registering routes on their router parameters.
+func (t *routerTracker) exportFacts() {
+	for _, file := range t.pass.Files {
+		c := t.collectors[file]
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				obj := t.pass.TypesInfo.Defs[d.Name]
+				if obj == nil {
+					continue
+				}
+				if d.Type.Results != nil {
+					if index, kind := c.routerResult(d.Type); index >= 0 {
+						t.exportRouterFact(obj, kind)
+					}
+				}
+				params := make(map[int]*Router)
+				for i, name := range paramNames(d) {
+					if r := t.params[t.pass.TypesInfo.Defs[name]]; r != nil && (len(r.Routes) > 0 || len(r.Middleware) > 0) {
+						params[i] = r
+					}
+				}
+				if len(params) > 0 {
+					t.pass.ExportObjectFact(obj, &RegistersRoutesFact{Params: params})
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					vs, ok := spec.(*ast.ValueSpec)
+					if !ok {
+						continue
+					}
+					for _, name := range vs.Names {
+						if obj := t.pass.TypesInfo.Defs[name]; obj != nil {
+							t.exportRouterFact(obj, c.routerType(vs.Type))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (t *routerTracker) exportRouterFact(obj types.Object, kind string) {
+	fact := &RouterFact{Kind: kind}
+	if routers := t.objs[obj]; len(routers) > 0 {
+		fact.Kind, fact.Router = routers[0].Kind, routers[0]
+	}
+	if fact.Kind != "" {
+		t.pass.ExportObjectFact(obj, fact)
+	}
+}
+
+// mergeRouter adds copies of the routes and the middleware of src, a
+// router of another package, to r.
+func mergeRouter(r, src *Router) {
+	r.Middleware = append(r.Middleware, src.Middleware...)
+	for _, route := range src.Routes {
+		cp := *route
+		r.Routes = append(r.Routes, &cp)
+	}
+}
+
+// paramNames returns the names of the parameters of fn, with nil for
+// unnamed ones, indexed by position.
+func paramNames(fn *ast.FuncDecl) (names []*ast.Ident) {
+	for _, field := range fn.Type.Params.List {
+		if len(field.Names) == 0 {
+			names = append(names, nil)
+		}
+		names = append(names, field.Names...)
+	}
+	return names
+}
+
+func containsRouter(routers []*Router, r *Router) bool {
+	for _, e := range routers {
+		if e == r {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRoute(routes []*Route, r *Route) bool {
+	for _, e := range routes {
+		if e == r {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExpr(exprs []ast.Expr, x ast.Expr) bool {
+	for _, e := range exprs {
+		if e == x {
+			return true
+		}
+	}
+	return false
+}
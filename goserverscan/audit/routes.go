@@ -0,0 +1,554 @@
+package audit
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// RoutesAnalyzer collects the HTTP routers of a package with their
+// routes and middleware.
+//
+// Routers are followed through variables, struct fields, return
+// values and parameters. It exports a RouterFact for package level
+// routers and functions returning a router, a RegistersRoutesFact for
+// functions registering routes on a router parameter, and an
+// AuthMiddlewareFact for functions wrapping handlers with
+// authentication, so that dependent packages can merge the routes
+// registered on a same router and resolve its middleware.
+var RoutesAnalyzer = &analysis.Analyzer{
+	Name:             "routes",
+	Doc:              "collect HTTP routers, their routes and middleware",
+	Run:              runRoutes,
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf((*Routes)(nil)),
+	FactTypes:        []analysis.Fact{new(RouterFact), new(RegistersRoutesFact), new(AuthMiddlewareFact)},
+}
+
+type Routes struct {
+	Routers []*Router
+
+	handlers map[*Route]ast.Expr
+}
+
+// RoutesWithMiddleware returns the routes wrapped, directly or through
+// their router, by the middleware function named name.
+func (rs *Routes) RoutesWithMiddleware(name string) (routes []*Route) {
+	matches := func(middleware []string) bool {
+		for _, mw := range middleware {
+			if i := strings.Index(mw, "("); i >= 0 {
+				mw = mw[:i]
+			}
+			if i := strings.LastIndex(mw, "."); i >= 0 {
+				mw = mw[i+1:]
+			}
+			if mw == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, router := range rs.Routers {
+		for _, route := range router.Routes {
+			if matches(router.Middleware) || matches(route.Middleware) {
+				routes = append(routes, route)
+			}
+		}
+	}
+	return routes
+}
+
+// routesReaching returns the routes whose handler, or middleware,
+// encloses node.
+func routesReaching(pass *analysis.Pass, routes *Routes, file *ast.File, node ast.Node) (reaching []*Route) {
+	path, _ := astutil.PathEnclosingInterval(file, node.Pos(), node.End())
+	for _, n := range path {
+		switch fn := n.(type) {
+		case *ast.FuncLit:
+			reaching = append(reaching, routes.RoutesServedBy(pass.TypesInfo, fn)...)
+		case *ast.FuncDecl:
+			reaching = append(reaching, routes.RoutesServedBy(pass.TypesInfo, fn)...)
+			reaching = append(reaching, routes.RoutesWithMiddleware(fn.Name.Name)...)
+		}
+	}
+
+	seen := make(map[*Route]bool)
+	uniq := reaching[:0]
+	for _, r := range reaching {
+		if !seen[r] {
+			seen[r] = true
+			uniq = append(uniq, r)
+		}
+	}
+	return uniq
+}
+
+// RoutesServedBy returns the routes whose handler is fn, a function
+// declaration or literal of the analysed package.
+func (rs *Routes) RoutesServedBy(info *types.Info, fn ast.Node) (routes []*Route) {
+	var obj types.Object
+	var name string
+	if decl, ok := fn.(*ast.FuncDecl); ok {
+		obj, name = info.Defs[decl.Name], decl.Name.Name
+	}
+	for _, router := range rs.Routers {
+		for _, route := range router.Routes {
+			h, ok := rs.handlers[route]
+			if !ok {
+				continue
+			}
+			if h == fn {
+				routes = append(routes, route)
+				continue
+			}
+			var ident *ast.Ident
+			switch e := h.(type) {
+			case *ast.Ident:
+				ident = e
+			case *ast.SelectorExpr:
+				ident = e.Sel
+			}
+			if ident == nil || name == "" {
+				continue
+			}
+			if used := info.Uses[ident]; (used != nil && used == obj) || (used == nil && ident.Name == name) {
+				routes = append(routes, route)
+			}
+		}
+	}
+	return routes
+}
+
+// RouterFact marks a package level variable holding a router, or a
+// function returning one. Router, when known, is the router instance
+// with the routes registered on it.
+type RouterFact struct {
+	Kind   string
+	Router *Router
+}
+
+func (*RouterFact) AFact() {}
+
+func (f *RouterFact) String() string { return "router(" + f.Kind + ")" }
+
+// AuthMiddlewareFact marks a function taking and returning a handler
+// that checks credentials before calling it.
+type AuthMiddlewareFact struct{}
+
+func (*AuthMiddlewareFact) AFact() {}
+
+func (*AuthMiddlewareFact) String() string { return "authMiddleware" }
+
+var authNameRE = regexp.MustCompile(`(?i)auth|jwt|session|token|bearer|oauth|apikey|login`)
+
+type routesCollector struct {
+	pass   *analysis.Pass
+	file   *ast.File
+	result *Routes
+}
+
+func runRoutes(pass *analysis.Pass) (interface{}, error) {
+	res := &Routes{handlers: make(map[*Route]ast.Expr)}
+
+	for _, file := range pass.Files {
+		c := &routesCollector{pass: pass, file: file, result: res}
+		c.exportFacts()
+	}
+
+	t := newRouterTracker(pass, res)
+	t.track()
+	t.register()
+	res.Routers = t.Routers()
+
+	for route, handler := range res.handlers {
+		route.HandlerLocation = handlerLocation(pass, handler)
+		route.Inputs = handlerInputs(pass, route, handler)
+	}
+	t.exportFacts()
+
+	return res, nil
+}
+
+// handlerLocation returns the file:line where the handler of a route,
+// or the factory returning it, is defined in the package.
+func handlerLocation(pass *analysis.Pass, handler ast.Expr) string {
+	var node ast.Node
+	switch h := handler.(type) {
+	case *ast.FuncLit:
+		node = h
+	case *ast.CallExpr:
+		if decl := resolveFuncDecl(pass, h.Fun); decl != nil {
+			node = decl
+		}
+	default:
+		if decl := resolveFuncDecl(pass, h); decl != nil {
+			node = decl
+		} else if name := handlerTypeName(pass, h); name != "" {
+			if decl := serveHTTPDecl(pass, name); decl != nil {
+				node = decl
+			} else if obj := pass.Pkg.Scope().Lookup(name); obj != nil {
+				// ServeHTTP promoted from an embedded field.
+				pos := pass.Fset.Position(obj.Pos())
+				return fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+			}
+		}
+	}
+	if node == nil {
+		return ""
+	}
+	pos := pass.Fset.Position(node.Pos())
+	return fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+}
+
+func (c *routesCollector) exportFacts() {
+	for _, decl := range c.file.Decls {
+		if d, ok := decl.(*ast.FuncDecl); ok && c.isAuthMiddleware(d) {
+			if obj := c.pass.TypesInfo.Defs[d.Name]; obj != nil {
+				c.pass.ExportObjectFact(obj, &AuthMiddlewareFact{})
+			}
+		}
+	}
+}
+
+// routerType returns the kind of router of a *mux.Router or
+// *http.ServeMux type expression.
+func (c *routesCollector) routerType(expr ast.Expr) string {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	switch {
+	case isPkgSelector(c.pass.TypesInfo, c.file, sel, "github.com/gorilla/mux", "Router"):
+		return "gorilla/mux"
+	case isPkgSelector(c.pass.TypesInfo, c.file, sel, "net/http", "ServeMux"):
+		return "net/http"
+	}
+	return ""
+}
+
+// routerResult returns the index and kind of the router returned by
+// functions of type ft: the only result, whatever its type, or else
+// the only router result. The index is -1 when there is none.
+func (c *routesCollector) routerResult(ft *ast.FuncType) (int, string) {
+	if ft.Results == nil {
+		return -1, ""
+	}
+	var kinds []string
+	for _, field := range ft.Results.List {
+		kind := c.routerType(field.Type)
+		for n := max(len(field.Names), 1); n > 0; n-- {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 1 {
+		return 0, kinds[0]
+	}
+	index := -1
+	for i, kind := range kinds {
+		if kind != "" {
+			if index >= 0 {
+				return -1, ""
+			}
+			index = i
+		}
+	}
+	if index < 0 {
+		return -1, ""
+	}
+	return index, kinds[index]
+}
+
+// routerConstructor returns the kind of router created by call.
+func (c *routesCollector) routerConstructor(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	switch {
+	case isPkgSelector(c.pass.TypesInfo, c.file, sel, "github.com/gorilla/mux", "NewRouter"):
+		return "gorilla/mux"
+	case isPkgSelector(c.pass.TypesInfo, c.file, sel, "net/http", "NewServeMux"):
+		return "net/http"
+	}
+	return ""
+}
+
+// splitPattern splits the method off a Go 1.22 ServeMux pattern
+// such as "POST /items/{id}".
+func splitPattern(pattern string) (method, path string, ok bool) {
+	i := strings.Index(pattern, " ")
+	if i < 0 {
+		return "", pattern, false
+	}
+	method = pattern[:i]
+	if method != strings.ToUpper(method) {
+		return "", pattern, false
+	}
+	return method, strings.TrimSpace(pattern[i+1:]), true
+}
+
+// unwrapHandler splits a handler expression such as
+// auth(logging(http.HandlerFunc(h))) into its middleware and handler.
+func (c *routesCollector) unwrapHandler(expr ast.Expr) (middleware []ast.Expr, handler ast.Expr) {
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		// http.StripPrefix("/static/", h) is kept as middleware with its
+		// other arguments.
+		if mw, inner, ok := unwrapStdWrapper(c.pass.TypesInfo, c.file, call); ok {
+			middleware = append(middleware, mw)
+			expr = inner
+			continue
+		}
+		if len(call.Args) != 1 {
+			break
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && isPkgSelector(c.pass.TypesInfo, c.file, sel, "net/http", "HandlerFunc") {
+			expr = call.Args[0]
+			continue
+		}
+		if t := typeOf(c.pass.TypesInfo, call.Args[0]); t != nil && !isHandlerType(t) {
+			break
+		}
+		middleware = append(middleware, call.Fun)
+		expr = call.Args[0]
+	}
+	return middleware, expr
+}
+
+// stdWrappers are the net/http functions wrapping a handler, with the
+// index of the handler argument.
+var stdWrappers = map[string]int{
+	"StripPrefix":     1,
+	"TimeoutHandler":  0,
+	"MaxBytesHandler": 0,
+}
+
+// unwrapStdWrapper splits a call to one of stdWrappers into the
+// wrapper, with its other arguments, and the wrapped handler.
+func unwrapStdWrapper(info *types.Info, file *ast.File, call *ast.CallExpr) (wrapper, handler ast.Expr, ok bool) {
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+	if !isSel {
+		return nil, nil, false
+	}
+	i, known := stdWrappers[sel.Sel.Name]
+	if !known || i >= len(call.Args) || !isPkgSelector(info, file, sel, "net/http", sel.Sel.Name) {
+		return nil, nil, false
+	}
+	args := append(append([]ast.Expr{}, call.Args[:i]...), call.Args[i+1:]...)
+	return &ast.CallExpr{Fun: call.Fun, Args: args}, call.Args[i], true
+}
+
+func anyAuthMiddleware(pass *analysis.Pass, middleware []ast.Expr) bool {
+	for _, mw := range middleware {
+		if isAuthMiddlewareExpr(pass, mw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuthMiddlewareExpr checks the facts of the function designated by
+// mw, or returning the middleware when mw is a call, and falls back
+// on its name when they do not mark it, as for dependencies that were
+// not analysed.
+func isAuthMiddlewareExpr(pass *analysis.Pass, mw ast.Expr) bool {
+	if call, ok := mw.(*ast.CallExpr); ok {
+		mw = call.Fun
+	}
+	var ident *ast.Ident
+	switch e := mw.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return false
+	}
+	if fn, ok := pass.TypesInfo.Uses[ident].(*types.Func); ok && pass.ImportObjectFact(fn, new(AuthMiddlewareFact)) {
+		return true
+	}
+	return authNameRE.MatchString(ident.Name)
+}
+
+// isAuthMiddleware reports whether f wraps a handler and either is
+// named like an authentication middleware or checks credentials.
+func (c *routesCollector) isAuthMiddleware(f *ast.FuncDecl) bool {
+	ft := f.Type
+	if f.Body == nil || ft.Params == nil || ft.Results == nil || len(ft.Params.List) != 1 || len(ft.Results.List) != 1 {
+		return false
+	}
+	if !c.isHandlerTypeExpr(ft.Params.List[0].Type) || !c.isHandlerTypeExpr(ft.Results.List[0].Type) {
+		return false
+	}
+	return authNameRE.MatchString(f.Name.Name) || checksCredentials(c.pass, f.Body)
+}
+
+// checksCredentials reports whether b calls another authentication
+// middleware, or branches on credentials read from a request to a
+// branch rejecting it. Middleware only reading a cookie or a header,
+// as for logging, does not check credentials.
+func checksCredentials(pass *analysis.Pass, b *ast.BlockStmt) (found bool) {
+	// creds are the names of the variables holding credentials.
+	creds := make(map[string]bool)
+	ast.Inspect(b, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			for _, rhs := range node.Rhs {
+				if readsCredentials(rhs, creds) {
+					for _, lhs := range node.Lhs {
+						if name := extractIdent(lhs); name != "" && name != "_" {
+							creds[name] = true
+						}
+					}
+				}
+			}
+		case *ast.IfStmt:
+			if (readsCredentials(node.Init, creds) || readsCredentials(node.Cond, creds)) && (rejects(pass, node.Body) || rejects(pass, node.Else)) {
+				found = true
+			}
+		case *ast.CallExpr:
+			var ident *ast.Ident
+			switch fun := node.Fun.(type) {
+			case *ast.Ident:
+				ident = fun
+			case *ast.SelectorExpr:
+				ident = fun.Sel
+			}
+			if fn, ok := pass.TypesInfo.Uses[ident].(*types.Func); ok && pass.ImportObjectFact(fn, new(AuthMiddlewareFact)) {
+				found = true
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// readsCredentials reports whether n reads the Authorization header,
+// basic authentication or a cookie, or uses a variable of creds.
+func readsCredentials(n ast.Node, creds map[string]bool) (found bool) {
+	if n == nil {
+		return false
+	}
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.BasicLit:
+			if strings.EqualFold(exprValue(node), "Authorization") {
+				found = true
+			}
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "BasicAuth" || sel.Sel.Name == "Cookie") {
+				found = true
+			}
+		case *ast.Ident:
+			if creds[node.Name] {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// rejects reports whether s returns or writes a 401 or 403 status,
+// without calling the next handler.
+func rejects(pass *analysis.Pass, s ast.Stmt) bool {
+	if s == nil {
+		return false
+	}
+	var next, returns, denies bool
+	ast.Inspect(s, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			returns = true
+		case *ast.CallExpr:
+			switch fun := node.Fun.(type) {
+			case *ast.SelectorExpr:
+				if fun.Sel.Name == "ServeHTTP" {
+					next = true
+				}
+			case *ast.Ident:
+				// Calls of handler values, as next(w, r).
+				if _, ok := pass.TypesInfo.Uses[fun].(*types.Var); ok {
+					next = true
+				}
+			}
+			for _, arg := range node.Args {
+				if isDeniedStatus(pass.TypesInfo, arg) {
+					denies = true
+				}
+			}
+		}
+		return true
+	})
+	return !next && (returns || denies)
+}
+
+// isDeniedStatus reports whether code is the 401 or 403 status.
+func isDeniedStatus(info *types.Info, code ast.Expr) bool {
+	if tv, ok := info.Types[code]; ok && tv.Value != nil && tv.Value.Kind() == constant.Int {
+		if v, exact := constant.Int64Val(tv.Value); exact {
+			return v == http.StatusUnauthorized || v == http.StatusForbidden
+		}
+	}
+	if sel, ok := code.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name == "StatusUnauthorized" || sel.Sel.Name == "StatusForbidden"
+	}
+	return false
+}
+
+func (c *routesCollector) isHandlerTypeExpr(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	return isPkgSelector(c.pass.TypesInfo, c.file, sel, "net/http", "Handler") ||
+		isPkgSelector(c.pass.TypesInfo, c.file, sel, "net/http", "HandlerFunc")
+}
+
+// isHandlerType reports whether t can serve HTTP requests: it has a
+// ServeHTTP method or is a func(http.ResponseWriter, *http.Request).
+func isHandlerType(t types.Type) bool {
+	if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "ServeHTTP"); obj != nil {
+		return true
+	}
+	sig, ok := t.Underlying().(*types.Signature)
+	if !ok || sig.Params().Len() != 2 {
+		return false
+	}
+	return isNamed(sig.Params().At(0).Type(), "net/http", "ResponseWriter") &&
+		isNamed(sig.Params().At(1).Type(), "net/http", "Request")
+}
+
+// isNamed reports whether t, or the type it points to, is the named
+// type path.name.
+func isNamed(t types.Type, path, name string) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == name && obj.Pkg() != nil && obj.Pkg().Path() == path
+}
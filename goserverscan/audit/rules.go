@@ -0,0 +1,328 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"gopkg.in/yaml.v3"
+)
+
+// RulesAnalyzer applies the rules found in the directory given with
+// its -dir flag. Use NewRulesAnalyzer to apply already loaded rules.
+var RulesAnalyzer = &analysis.Analyzer{
+	Name:             "rules",
+	Doc:              "apply user-defined YAML pattern rules",
+	Run:              runRulesFromFlag,
+	RunDespiteErrors: true,
+}
+
+var (
+	rulesDirFlag  string
+	loadRulesOnce sync.Once
+	flagRules     []*Rule
+	flagRulesErr  error
+)
+
+func init() {
+	RulesAnalyzer.Flags.StringVar(&rulesDirFlag, "dir", "", "Dir of YAML pattern rules to apply")
+}
+
+func runRulesFromFlag(pass *analysis.Pass) (interface{}, error) {
+	if rulesDirFlag == "" {
+		return nil, nil
+	}
+	loadRulesOnce.Do(func() {
+		flagRules, _, flagRulesErr = LoadRules(rulesDirFlag)
+	})
+	if flagRulesErr != nil {
+		return nil, flagRulesErr
+	}
+	return applyRules(pass, flagRules)
+}
+
+// NewRulesAnalyzer returns an analyzer applying rules.
+func NewRulesAnalyzer(rules []*Rule) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "rules",
+		Doc:  "apply user-defined YAML pattern rules",
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return applyRules(pass, rules)
+		},
+		RunDespiteErrors: true,
+	}
+}
+
+func applyRules(pass *analysis.Pass, rules []*Rule) (interface{}, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			// Requests are only sources within function bodies.
+			var src sources
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				src = requestSources(fn)
+			}
+			ast.Inspect(decl, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					for _, r := range rules {
+						if r.matchCall(pass.TypesInfo, file, call, src) {
+							report(pass, call, r.check, "%s", r.Message)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return nil, nil
+}
+
+// Rule is a declarative check matching calls to a package function
+// or to a method, with optional constraints on the call arguments.
+//
+//	rules:
+//	  - id: sql-injection
+//	    package: database/sql
+//	    method: Query
+//	    args:
+//	      - index: 0
+//	        from-source: true
+//	    severity: high
+//	    confidence: medium
+//	    message: SQL query built from request data
+//	    cwe: CWE-89
+type Rule struct {
+	ID         string           `yaml:"id"`
+	Package    string           `yaml:"package"`
+	Function   string           `yaml:"function"`
+	Method     string           `yaml:"method"`
+	Args       []*ArgConstraint `yaml:"args"`
+	Severity   Severity         `yaml:"severity"`
+	Confidence Confidence       `yaml:"confidence"`
+	Message    string           `yaml:"message"`
+	CWE        string           `yaml:"cwe"`
+
+	check *Check
+}
+
+// ArgConstraint applies to the call argument at Index. All the
+// constraints set must hold for the argument to match.
+type ArgConstraint struct {
+	Index      int    `yaml:"index"`
+	Constant   *bool  `yaml:"constant"`
+	FromSource *bool  `yaml:"from-source"`
+	Matches    string `yaml:"matches"`
+
+	re *regexp.Regexp
+}
+
+type rulesFile struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// LoadRules reads all the rules in the .yml and .yaml files of dir.
+// It also returns a hash of the files content to identify the rule set.
+func LoadRules(dir string) ([]*Rule, string, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, "", err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var rules []*Rule
+	ids := make(map[string]string)
+	h := sha256.New()
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		h.Write(b)
+
+		var file rulesFile
+		if err := yaml.Unmarshal(b, &file); err != nil {
+			return nil, "", fmt.Errorf("%s: %s", path, err)
+		}
+		for _, r := range file.Rules {
+			if err := r.compile(); err != nil {
+				return nil, "", fmt.Errorf("%s: %s", path, err)
+			}
+			if other, ok := ids[r.ID]; ok {
+				return nil, "", fmt.Errorf("%s: rule %q already defined in %s", path, r.ID, other)
+			}
+			ids[r.ID] = path
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *Rule) compile() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule without id")
+	}
+	if (r.Function == "") == (r.Method == "") {
+		return fmt.Errorf("rule %q: exactly one of function or method is required", r.ID)
+	}
+	if r.Function != "" && r.Package == "" {
+		return fmt.Errorf("rule %q: function %q without package", r.ID, r.Function)
+	}
+	if r.Severity == "" {
+		r.Severity = SeverityMedium
+	}
+	sev, err := ParseSeverity(string(r.Severity))
+	if err != nil {
+		return fmt.Errorf("rule %q: %s", r.ID, err)
+	}
+	r.Severity = sev
+	if r.Confidence == "" {
+		r.Confidence = ConfidenceMedium
+	}
+	conf, err := ParseConfidence(string(r.Confidence))
+	if err != nil {
+		return fmt.Errorf("rule %q: %s", r.ID, err)
+	}
+	r.Confidence = conf
+	if c, ok := LookupCheck(r.ID); ok && !c.userRule {
+		return fmt.Errorf("rule %q: id of a built-in check", r.ID)
+	}
+	r.check = RegisterCheck(&Check{
+		ID:         r.ID,
+		Severity:   r.Severity,
+		Confidence: r.Confidence,
+		CWE:        r.CWE,
+		userRule:   true,
+	})
+	for _, arg := range r.Args {
+		if arg.Index < 0 {
+			return fmt.Errorf("rule %q: negative argument index %d", r.ID, arg.Index)
+		}
+		if arg.Matches != "" {
+			re, err := regexp.Compile(arg.Matches)
+			if err != nil {
+				return fmt.Errorf("rule %q: %s", r.ID, err)
+			}
+			arg.re = re
+		}
+	}
+	return nil
+}
+
+// matchCall reports whether call is the function or method named by
+// the rule and satisfies all its argument constraints. Methods match
+// on the package of their receiver when the call is type-checked, and
+// on the file importing the package otherwise.
+func (r *Rule) matchCall(info *types.Info, file *ast.File, call *ast.CallExpr, src sources) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	var importPath string
+	var isPackage bool
+	if ident, ok := sel.X.(*ast.Ident); ok {
+		importPath, isPackage = importedPath(info, file, ident)
+	}
+	if r.Function != "" {
+		if !isPackage || importPath != r.Package || sel.Sel.Name != r.Function {
+			return false
+		}
+	} else {
+		if isPackage || sel.Sel.Name != r.Method {
+			return false
+		}
+		if selection, ok := info.Selections[sel]; ok {
+			if r.Package != "" && !isMethodOf(selection, r.Package) {
+				return false
+			}
+		} else if r.Package != "" && !importsPath(file, r.Package) {
+			return false
+		}
+	}
+
+	for _, arg := range r.Args {
+		if arg.Index >= len(call.Args) || !arg.match(info, call.Args[arg.Index], src) {
+			return false
+		}
+	}
+	return true
+}
+
+// isMethodOf reports whether the method selected is declared in, or
+// called on a type of, the package at path.
+func isMethodOf(selection *types.Selection, path string) bool {
+	if fn, ok := selection.Obj().(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == path {
+		return true
+	}
+	recv := selection.Recv()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == path
+}
+
+func (c *ArgConstraint) match(info *types.Info, expr ast.Expr, src sources) bool {
+	if c.Constant != nil && isConstant(info, expr) != *c.Constant {
+		return false
+	}
+	if c.FromSource != nil && src.contains(expr) != *c.FromSource {
+		return false
+	}
+	if c.re != nil && !c.re.MatchString(exprValue(expr)) {
+		return false
+	}
+	return true
+}
+
+// isConstant reports whether expr is a constant expression, from its
+// type information or, when it is missing, from its syntax.
+func isConstant(info *types.Info, expr ast.Expr) bool {
+	if tv, ok := info.Types[expr]; ok {
+		return tv.Value != nil
+	}
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.ParenExpr:
+		return isConstant(info, e.X)
+	case *ast.BinaryExpr:
+		return isConstant(info, e.X) && isConstant(info, e.Y)
+	case *ast.Ident:
+		_, ok := info.Uses[e].(*types.Const)
+		return ok
+	}
+	return false
+}
+
+// exprValue returns the unquoted value of string literals and the
+// source code of any other expression.
+func exprValue(expr ast.Expr) string {
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s
+		}
+	}
+	var b bytes.Buffer
+	printer.Fprint(&b, token.NewFileSet(), expr)
+	return b.String()
+}
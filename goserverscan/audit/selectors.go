@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// importedPath returns the import path of the package designated by
+// ident in a qualified identifier such as http in http.Get.
+//
+// Type information is used when available. Otherwise, as when scanned
+// code misses its dependencies, the imports of file are used.
+func importedPath(info *types.Info, file *ast.File, ident *ast.Ident) (string, bool) {
+	if info != nil {
+		if obj, ok := info.Uses[ident]; ok {
+			if pkg, ok := obj.(*types.PkgName); ok {
+				return pkg.Imported().Path(), true
+			}
+			return "", false
+		}
+	}
+	if file == nil {
+		return "", false
+	}
+	path, ok := fileImports(file)[ident.Name]
+	return path, ok
+}
+
+// isPkgSelector reports whether sel is the qualified identifier
+// name of the package imported with path.
+func isPkgSelector(info *types.Info, file *ast.File, sel *ast.SelectorExpr, path, name string) bool {
+	if sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	if p, ok := importedPath(info, file, ident); ok {
+		return p == path
+	}
+	return ident.Name == defaultImportName(path)
+}
+
+// pkgFunc returns the import path and name of the package level
+// function or type called in call, if any.
+func pkgFunc(info *types.Info, file *ast.File, call *ast.CallExpr) (path, name string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	if p, ok := importedPath(info, file, ident); ok {
+		return p, sel.Sel.Name
+	}
+	return "", ""
+}
+
+// typeOf returns the type of expr, or nil when it is unknown as for
+// expressions using packages that could not be imported.
+func typeOf(info *types.Info, expr ast.Expr) types.Type {
+	if info == nil {
+		return nil
+	}
+	t := info.TypeOf(expr)
+	for elem := t; elem != nil; {
+		if elem == types.Typ[types.Invalid] {
+			return nil
+		}
+		ptr, ok := elem.(*types.Pointer)
+		if !ok {
+			break
+		}
+		elem = ptr.Elem()
+	}
+	return t
+}
+
+func isSelectorExpr(sel *ast.SelectorExpr, exprIdent, selIdent string) bool {
+	return extractIdent(sel.X) == exprIdent && sel.Sel.Name == selIdent
+}
+
+// sameVar reports whether a and b designate the same variable or
+// field of the same variable, as s.grpc in s.grpc = grpc.NewServer()
+// and pb.RegisterServer(s.grpc, srv). Identifiers are compared by
+// object when type-checked and by name otherwise.
+func sameVar(info *types.Info, a, b ast.Expr) bool {
+	a, b = ast.Unparen(a), ast.Unparen(b)
+	switch a := a.(type) {
+	case *ast.Ident:
+		b, ok := b.(*ast.Ident)
+		if !ok || a.Name == "_" || a.Name != b.Name {
+			return false
+		}
+		oa, ob := info.ObjectOf(a), info.ObjectOf(b)
+		return oa == nil || ob == nil || oa == ob
+	case *ast.SelectorExpr:
+		b, ok := b.(*ast.SelectorExpr)
+		return ok && a.Sel.Name == b.Sel.Name && sameVar(info, a.X, b.X)
+	case *ast.StarExpr:
+		b, ok := b.(*ast.StarExpr)
+		return ok && sameVar(info, a.X, b.X)
+	}
+	return false
+}
+
+func extractIdent(n ast.Node) string {
+	switch ident := n.(type) {
+	case *ast.Ident:
+		return ident.Name
+	}
+	return ""
+}
+
+// fileImports maps the names under which packages are imported in f
+// to their import path.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var name string
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else {
+			name = defaultImportName(path)
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// defaultImportName guesses the package name from its import path,
+// skipping major version suffixes as in github.com/go-chi/chi/v5 or
+// gopkg.in/yaml.v3.
+func defaultImportName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+func importsPath(f *ast.File, path string) bool {
+	for _, spec := range f.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,457 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// ServicesAnalyzer collects gRPC servers with their RPCs and
+// interceptors, and GraphQL servers with their resolvers and the
+// routes they are mounted on.
+var ServicesAnalyzer = &analysis.Analyzer{
+	Name:             "services",
+	Doc:              "collect gRPC and GraphQL services and report those without authentication",
+	Run:              runServices,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf((*Routes)(nil)),
+}
+
+var (
+	checkGRPCUnauthenticated = RegisterCheck(&Check{
+		ID:         "grpc-unauthenticated",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceLow,
+		CWE:        "CWE-306",
+	})
+	checkGraphQLUnauthenticated = RegisterCheck(&Check{
+		ID:         "graphql-unauthenticated",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceLow,
+		CWE:        "CWE-306",
+	})
+)
+
+const (
+	grpcPath          = "google.golang.org/grpc"
+	gqlgenHandlerPath = "github.com/99designs/gqlgen/graphql/handler"
+	gophersPath       = "github.com/graph-gophers/graphql-go"
+	gophersRelayPath  = "github.com/graph-gophers/graphql-go/relay"
+)
+
+var registerServerRE = regexp.MustCompile(`^Register(\w+)Server$`)
+
+type servicesCollector struct {
+	pass   *analysis.Pass
+	file   *ast.File
+	routes *Routes
+	result *Routes
+}
+
+func runServices(pass *analysis.Pass) (interface{}, error) {
+	res := &Routes{}
+	for _, file := range pass.Files {
+		c := &servicesCollector{
+			pass:   pass,
+			file:   file,
+			routes: pass.ResultOf[RoutesAnalyzer].(*Routes),
+			result: res,
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				c.collectGRPCServers(fn.Body)
+				c.collectGraphQLServers(fn.Body)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (c *servicesCollector) collectGRPCServers(body *ast.BlockStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		server, call := assignedCall(n)
+		if call == nil || !c.isPkgCall(call, grpcPath, "NewServer") {
+			return true
+		}
+
+		router := &Router{
+			Snippet: NewSnippet(c.pass.Fset, n),
+			Kind:    "grpc",
+		}
+		var authenticated bool
+		for _, interceptor := range c.interceptors(body, call.Args) {
+			router.Middleware = append(router.Middleware, interceptor.kind+":"+exprValue(interceptor.fn))
+			authenticated = authenticated || c.isAuthInterceptor(interceptor.fn)
+		}
+
+		ast.Inspect(body, func(n ast.Node) bool {
+			reg, ok := n.(*ast.CallExpr)
+			if !ok || len(reg.Args) != 2 || !sameVar(c.pass.TypesInfo, reg.Args[0], server) {
+				return true
+			}
+			m := registerServerRE.FindStringSubmatch(calleeName(reg))
+			if m == nil {
+				return true
+			}
+			for _, method := range c.implMethods(reg.Args[1]) {
+				if strings.HasPrefix(method, "mustEmbed") {
+					continue
+				}
+				router.Routes = append(router.Routes, &Route{
+					Snippet:       NewSnippet(c.pass.Fset, reg),
+					Path:          "/" + m[1] + "/" + method,
+					Middleware:    router.Middleware,
+					Handler:       exprValue(reg.Args[1]) + "." + method,
+					Authenticated: authenticated,
+				})
+			}
+			return true
+		})
+
+		if !authenticated && len(router.Routes) > 0 {
+			report(c.pass, call, checkGRPCUnauthenticated, "gRPC server without authentication interceptor exposes %d RPCs", len(router.Routes))
+		}
+		c.result.Routers = append(c.result.Routers, router)
+		return true
+	})
+}
+
+type interceptor struct {
+	kind string
+	fn   ast.Expr
+}
+
+// interceptors returns the interceptors configured by the server
+// options opts, following option slices declared in body.
+func (c *servicesCollector) interceptors(body *ast.BlockStmt, opts []ast.Expr) (found []interceptor) {
+	for _, opt := range opts {
+		if ident, ok := opt.(*ast.Ident); ok {
+			found = append(found, c.interceptors(body, sliceElements(body, ident.Name))...)
+			continue
+		}
+		call, ok := opt.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		switch {
+		case c.isPkgCall(call, grpcPath, "UnaryInterceptor"), c.isPkgCall(call, grpcPath, "ChainUnaryInterceptor"):
+			for _, fn := range call.Args {
+				found = append(found, interceptor{"unary", fn})
+			}
+		case c.isPkgCall(call, grpcPath, "StreamInterceptor"), c.isPkgCall(call, grpcPath, "ChainStreamInterceptor"):
+			for _, fn := range call.Args {
+				found = append(found, interceptor{"stream", fn})
+			}
+		}
+	}
+	return found
+}
+
+// sliceElements returns the elements assigned or appended to the
+// slice variable name in body.
+func sliceElements(body *ast.BlockStmt, name string) (elts []ast.Expr) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		lhs, rhs := assignedValue(n, name)
+		switch v := rhs.(type) {
+		case *ast.CompositeLit:
+			elts = append(elts, v.Elts...)
+		case *ast.CallExpr:
+			if extractIdent(v.Fun) == "append" && len(v.Args) > 1 && extractIdent(v.Args[0]) == lhs {
+				elts = append(elts, v.Args[1:]...)
+			}
+		}
+		return true
+	})
+	return elts
+}
+
+func (c *servicesCollector) isAuthInterceptor(fn ast.Expr) bool {
+	if call, ok := fn.(*ast.CallExpr); ok {
+		fn = call.Fun
+	}
+	var ident *ast.Ident
+	switch e := fn.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return false
+	}
+	if authNameRE.MatchString(ident.Name) {
+		return true
+	}
+	if decl := funcDecl(c.pass, c.pass.TypesInfo.Uses[ident]); decl != nil && decl.Body != nil {
+		return checksCredentials(c.pass, decl.Body)
+	}
+	return false
+}
+
+func (c *servicesCollector) collectGraphQLServers(body *ast.BlockStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		lhs, call := assignedCall(n)
+		if call == nil {
+			return true
+		}
+		name := exprValue(lhs)
+
+		var kind string
+		var resolver ast.Expr
+		switch {
+		case c.isPkgCall(call, gqlgenHandlerPath, "NewDefaultServer"), c.isPkgCall(call, gqlgenHandlerPath, "New"):
+			kind, resolver = "graphql/gqlgen", gqlgenResolver(call)
+		case c.isPkgCall(call, gophersPath, "MustParseSchema"), c.isPkgCall(call, gophersPath, "ParseSchema"):
+			if len(call.Args) > 1 {
+				kind, resolver = "graphql/graph-gophers", call.Args[1]
+			}
+		}
+		if kind == "" {
+			return true
+		}
+
+		router := &Router{
+			Snippet: NewSnippet(c.pass.Fset, n),
+			Kind:    kind,
+		}
+		mount := c.mountRoute(body, name, kind)
+		if mount != nil {
+			router.Mount = mount.Path
+			router.Middleware = mount.Middleware
+		}
+		if resolver != nil {
+			for _, field := range c.resolvers(resolver) {
+				route := &Route{
+					Snippet: NewSnippet(c.pass.Fset, resolver),
+					Path:    field,
+					Handler: exprValue(resolver) + "." + field,
+				}
+				if mount != nil {
+					route.Methods = mount.Methods
+					route.Middleware = mount.Middleware
+					route.Authenticated = mount.Authenticated
+				}
+				router.Routes = append(router.Routes, route)
+			}
+		}
+
+		if mount != nil && !mount.Authenticated && len(router.Routes) > 0 {
+			report(c.pass, call, checkGraphQLUnauthenticated, "GraphQL server mounted on %s without authentication middleware exposes %d resolvers", mount.Path, len(router.Routes))
+		}
+		c.result.Routers = append(c.result.Routers, router)
+		return true
+	})
+}
+
+// gqlgenResolver returns the Resolvers field of the generated config
+// in handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: r})).
+func gqlgenResolver(call *ast.CallExpr) (resolver ast.Expr) {
+	ast.Inspect(call, func(n ast.Node) bool {
+		if kv, ok := n.(*ast.KeyValueExpr); ok && extractIdent(kv.Key) == "Resolvers" {
+			resolver = kv.Value
+			return false
+		}
+		return resolver == nil
+	})
+	return resolver
+}
+
+// mountRoute returns the route serving the GraphQL server held in
+// name, directly or through a graph-gophers relay.Handler.
+func (c *servicesCollector) mountRoute(body *ast.BlockStmt, name, kind string) *Route {
+	handlers := map[string]bool{name: true}
+	if kind == "graphql/graph-gophers" {
+		ast.Inspect(body, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.CompositeLit); ok {
+				if sel, ok := lit.Type.(*ast.SelectorExpr); ok && isPkgSelector(c.pass.TypesInfo, c.file, sel, gophersRelayPath, "Handler") {
+					for _, elt := range lit.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok && extractIdent(kv.Value) == name {
+							handlers[exprValue(lit)] = true
+							handlers["&"+exprValue(lit)] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	for _, router := range c.routes.Routers {
+		for _, route := range router.Routes {
+			if handlers[route.Handler] && c.pass.Fset.File(body.Pos()).Name() == route.Filename {
+				return route
+			}
+		}
+	}
+	return nil
+}
+
+// resolvers lists the methods of the resolver type. Methods returning
+// an interface, as the Query and Mutation methods generated by gqlgen,
+// are expanded to the methods of that interface.
+func (c *servicesCollector) resolvers(resolver ast.Expr) (fields []string) {
+	t := typeOf(c.pass.TypesInfo, resolver)
+	if t == nil {
+		return c.implMethods(resolver)
+	}
+	for _, m := range declaredMethods(t) {
+		sig := m.Type().(*types.Signature)
+		if sig.Params().Len() == 0 && sig.Results().Len() == 1 {
+			if iface, ok := sig.Results().At(0).Type().Underlying().(*types.Interface); ok {
+				for i := 0; i < iface.NumMethods(); i++ {
+					fields = append(fields, m.Name()+"."+iface.Method(i).Name())
+				}
+				continue
+			}
+		}
+		fields = append(fields, m.Name())
+	}
+	return fields
+}
+
+// implMethods returns the exported methods declared on the type of
+// impl, from type information or from the method declarations of the
+// package when the type is unknown.
+func (c *servicesCollector) implMethods(impl ast.Expr) (methods []string) {
+	if t := typeOf(c.pass.TypesInfo, impl); t != nil {
+		if _, ok := derefNamed(t); ok {
+			for _, m := range declaredMethods(t) {
+				methods = append(methods, m.Name())
+			}
+			return methods
+		}
+	}
+
+	typeName := compositeTypeName(impl)
+	if typeName == "" {
+		return nil
+	}
+	for _, file := range c.pass.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if ok && fn.Recv != nil && fn.Name.IsExported() && receiverTypeName(fn) == typeName {
+				methods = append(methods, fn.Name.Name)
+			}
+		}
+	}
+	return methods
+}
+
+func (c *servicesCollector) isPkgCall(call *ast.CallExpr, path, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && isPkgSelector(c.pass.TypesInfo, c.file, sel, path, name)
+}
+
+func derefNamed(t types.Type) (*types.Named, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return named, ok
+}
+
+// declaredMethods returns the exported methods declared on the named
+// type of t, leaving out those promoted from embedded fields such as
+// the Unimplemented servers generated by protoc-gen-go-grpc.
+func declaredMethods(t types.Type) (methods []*types.Func) {
+	named, ok := derefNamed(t)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		if m := named.Method(i); m.Exported() {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// assignedCall returns the variable and the call of statements such
+// as `s := pkg.F()`, `s.f = pkg.F()` or `var s = pkg.F()`.
+func assignedCall(n ast.Node) (ast.Expr, *ast.CallExpr) {
+	switch stmt := n.(type) {
+	case *ast.AssignStmt:
+		if len(stmt.Lhs) >= 1 && len(stmt.Rhs) == 1 {
+			if call, ok := stmt.Rhs[0].(*ast.CallExpr); ok {
+				return stmt.Lhs[0], call
+			}
+		}
+	case *ast.ValueSpec:
+		if len(stmt.Names) >= 1 && len(stmt.Values) == 1 {
+			if call, ok := stmt.Values[0].(*ast.CallExpr); ok {
+				return stmt.Names[0], call
+			}
+		}
+	}
+	return nil, nil
+}
+
+// assignedValue returns the value assigned to the variable name by n.
+func assignedValue(n ast.Node, name string) (string, ast.Expr) {
+	switch stmt := n.(type) {
+	case *ast.AssignStmt:
+		for i, lhs := range stmt.Lhs {
+			if extractIdent(lhs) == name && i < len(stmt.Rhs) {
+				return name, stmt.Rhs[i]
+			}
+		}
+	case *ast.ValueSpec:
+		for i, ident := range stmt.Names {
+			if ident.Name == name && i < len(stmt.Values) {
+				return name, stmt.Values[i]
+			}
+		}
+	}
+	return "", nil
+}
+
+func calleeName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return ""
+}
+
+// compositeTypeName returns the type name of &T{} or T{} expressions.
+func compositeTypeName(expr ast.Expr) string {
+	if u, ok := expr.(*ast.UnaryExpr); ok {
+		expr = u.X
+	}
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		return extractIdent(lit.Type)
+	}
+	return ""
+}
+
+func receiverTypeName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	t := fn.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	return extractIdent(t)
+}
+
+// funcDecl returns the declaration of the function obj when it is
+// declared in the analysed package.
+func funcDecl(pass *analysis.Pass, obj types.Object) *ast.FuncDecl {
+	if obj == nil {
+		return nil
+	}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && pass.TypesInfo.Defs[fn.Name] == obj {
+				return fn
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities are ordered from the least to the most severe.
+var Severities = []Severity{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range Severities {
+		if strings.EqualFold(s, string(sev)) {
+			return sev, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity %q", s)
+}
+
+func (s Severity) level() int {
+	for i, sev := range Severities {
+		if s == sev {
+			return i
+		}
+	}
+	return -1
+}
+
+// AtLeast reports whether s is as severe as or more severe than t.
+func (s Severity) AtLeast(t Severity) bool {
+	return s.level() >= t.level()
+}
+
+type Confidence string
+
+const (
+	ConfidenceLow    Confidence = "low"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceHigh   Confidence = "high"
+)
+
+func ParseConfidence(s string) (Confidence, error) {
+	for _, c := range []Confidence{ConfidenceLow, ConfidenceMedium, ConfidenceHigh} {
+		if strings.EqualFold(s, string(c)) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown confidence %q", s)
+}
@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// sources is the set of identifiers, within a function, holding data
+// that comes from an incoming HTTP request, or from another untrusted
+// origin such as a database.
+//
+// Identifiers are tracked by name: shadowing is ignored on purpose as
+// it would mostly hide real flows in handler code.
+type sources map[string]bool
+
+func requestSources(fn *ast.FuncDecl) sources {
+	s := make(sources)
+	if fn.Body == nil {
+		return s
+	}
+
+	s.addRequestParams(fn.Type)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			s.addRequestParams(lit.Type)
+		}
+		return true
+	})
+
+	s.propagate(fn.Body)
+	return s
+}
+
+// propagate taints the identifiers assigned from tainted ones in body,
+// until nothing new is tainted so that assignments appearing before
+// their source in the file are also caught.
+func (s sources) propagate(body *ast.BlockStmt) {
+	for changed := true; changed; {
+		changed = false
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if s.anyContains(node.Rhs) {
+					changed = s.taint(node.Lhs...) || changed
+				}
+			case *ast.ValueSpec:
+				if s.anyContains(node.Values) {
+					for _, name := range node.Names {
+						changed = s.taint(name) || changed
+					}
+				}
+			case *ast.RangeStmt:
+				if s.contains(node.X) {
+					changed = s.taint(node.Key, node.Value) || changed
+				}
+			case *ast.CallExpr:
+				// Decoding functions fill in their pointer arguments:
+				// json.NewDecoder(r.Body).Decode(&v), json.Unmarshal(b, &v)
+				if s.contains(node.Fun) || s.anyContains(node.Args) {
+					for _, arg := range node.Args {
+						if u, ok := arg.(*ast.UnaryExpr); ok && u.Op == token.AND {
+							changed = s.taint(u.X) || changed
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// databaseSources returns the identifiers, within fn, holding data read
+// from a database: the destinations of database/sql Scan calls and what
+// is assigned from them.
+func databaseSources(info *types.Info, fn *ast.FuncDecl) sources {
+	s := make(sources)
+	if fn.Body == nil {
+		return s
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Scan" {
+			return true
+		}
+		if t := typeOf(info, sel.X); t != nil && (isNamed(t, "database/sql", "Rows") || isNamed(t, "database/sql", "Row")) {
+			for _, arg := range call.Args {
+				if u, ok := arg.(*ast.UnaryExpr); ok && u.Op == token.AND {
+					s.taint(u.X)
+				}
+			}
+		}
+		return true
+	})
+	if len(s) > 0 {
+		s.propagate(fn.Body)
+	}
+	return s
+}
+
+func (s sources) addRequestParams(ft *ast.FuncType) {
+	if ft == nil || ft.Params == nil {
+		return
+	}
+	for _, field := range ft.Params.List {
+		if star, ok := field.Type.(*ast.StarExpr); ok {
+			if sel, ok := star.X.(*ast.SelectorExpr); ok && isSelectorExpr(sel, "http", "Request") {
+				for _, name := range field.Names {
+					s[name.Name] = true
+				}
+			}
+		}
+	}
+}
+
+func (s sources) taint(exprs ...ast.Expr) (changed bool) {
+	for _, expr := range exprs {
+		name := extractIdent(rootExpr(expr))
+		if name == "" || name == "_" || s[name] {
+			continue
+		}
+		s[name] = true
+		changed = true
+	}
+	return changed
+}
+
+func (s sources) anyContains(exprs []ast.Expr) bool {
+	for _, expr := range exprs {
+		if s.contains(expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether expr references a tainted identifier.
+func (s sources) contains(expr ast.Expr) (found bool) {
+	if expr == nil || len(s) == 0 {
+		return false
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			found = s.contains(node.X)
+			return false
+		case *ast.Ident:
+			found = s[node.Name]
+		}
+		return true
+	})
+	return found
+}
+
+// rootExpr returns the leftmost operand of selectors, index and star
+// expressions so that tainting `req.Name` or `*p` taints `req` and `p`.
+func rootExpr(expr ast.Expr) ast.Expr {
+	for {
+		switch e := expr.(type) {
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return expr
+		}
+	}
+}
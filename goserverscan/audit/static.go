@@ -0,0 +1,481 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// StaticAnalyzer finds the handlers serving files, http.FileServer
+// and the Static mounts of gin and echo, and resolves the directory
+// or embedded files served and the route prefix. It reports directory
+// listings, dotfiles and roots exposing source, .git, configuration or
+// template files.
+var StaticAnalyzer = &analysis.Analyzer{
+	Name:             "static",
+	Doc:              "list static file mounts and report directory listings, dotfiles and sensitive roots",
+	Run:              runStatic,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf([]*StaticMount(nil)),
+}
+
+var (
+	checkStaticListing = RegisterCheck(&Check{
+		ID:         "static-directory-listing",
+		Severity:   SeverityLow,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-548",
+	})
+	checkStaticDotfiles = RegisterCheck(&Check{
+		ID:         "static-dotfiles",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-538",
+	})
+	checkStaticSensitiveRoot = RegisterCheck(&Check{
+		ID:         "static-sensitive-root",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-219",
+	})
+)
+
+const (
+	ginPath  = "github.com/gin-gonic/gin"
+	echoPath = "github.com/labstack/echo/v4"
+)
+
+// maxStaticFiles bounds the files listed for a mount.
+const maxStaticFiles = 1000
+
+func runStatic(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	var mounts []*StaticMount
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if m := staticMount(pass, file, routes, call); m != nil {
+				checkStaticMount(pass, call, m)
+				mounts = append(mounts, m)
+			}
+			return true
+		})
+	}
+	return mounts, nil
+}
+
+// staticRoot is what a file server serves.
+type staticRoot struct {
+	dir     string   // directory, as written in the sources
+	embed   []string // or patterns of an embed.FS
+	sub     string   // directory of the embedded files after fs.Sub
+	listing bool
+}
+
+// staticMount returns the mount of call when it serves files.
+func staticMount(pass *analysis.Pass, file *ast.File, routes *Routes, call *ast.CallExpr) *StaticMount {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) == 0 {
+		return nil
+	}
+	last := call.Args[len(call.Args)-1]
+	m := &StaticMount{Snippet: NewSnippet(pass.Fset, call)}
+	var root staticRoot
+	switch {
+	case isPkgSelector(pass.TypesInfo, file, sel, "net/http", "FileServer"),
+		isPkgSelector(pass.TypesInfo, file, sel, "net/http", "FileServerFS"):
+		m.Kind, m.Prefix = "net/http", staticPrefix(pass, file, routes, call)
+		root = resolveFS(pass, file, call.Args[0])
+		root.listing = root.listing || sel.Sel.Name == "FileServerFS"
+	case isFrameworkCall(pass, file, sel, ginPath, []string{"Engine", "RouterGroup"}):
+		// gin.Static disables listings, StaticFS serves the file system
+		// as is.
+		m.Kind = "gin"
+		switch sel.Sel.Name {
+		case "Static", "StaticFile":
+			root.dir = exprValue(last)
+		case "StaticFS":
+			root = resolveFS(pass, file, last)
+		default:
+			return nil
+		}
+	case isFrameworkCall(pass, file, sel, echoPath, []string{"Echo", "Group"}):
+		m.Kind = "echo"
+		switch sel.Sel.Name {
+		case "Static", "File":
+			root.dir = exprValue(last)
+		case "StaticFS":
+			root = resolveFS(pass, file, last)
+			root.listing = false
+		default:
+			return nil
+		}
+	default:
+		return nil
+	}
+	if m.Kind != "net/http" {
+		if len(call.Args) < 2 {
+			return nil
+		}
+		m.Prefix = exprValue(call.Args[0])
+	}
+
+	m.Root, m.Listing = root.dir, root.listing
+	if root.embed != nil {
+		m.Embed, m.Root = true, strings.Join(root.embed, " ")
+		if root.sub != "" {
+			m.Root = root.sub
+		}
+	}
+	m.Files = staticFiles(filepath.Dir(pass.Fset.File(file.Pos()).Name()), root)
+	return m
+}
+
+// isFrameworkCall reports whether sel is a method call on one of the
+// types of the package path. When types are missing, the receiver is
+// expected to come from a function of the package, as r := gin.New(),
+// or else from any file importing it.
+func isFrameworkCall(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr, path string, typeNames []string) bool {
+	t := typeOf(pass.TypesInfo, sel.X)
+	if t == nil {
+		if !importsPath(file, path) {
+			return false
+		}
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if v, ok := pass.TypesInfo.Uses[ident].(*types.Var); ok {
+				if call, ok := valueOf(pass, v).(*ast.CallExpr); ok {
+					fun, ok := call.Fun.(*ast.SelectorExpr)
+					return ok && isPkgSelector(pass.TypesInfo, file, fun, path, fun.Sel.Name)
+				}
+			}
+		}
+		return true
+	}
+	for _, name := range typeNames {
+		if isNamed(t, path, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFS returns the root of fsys, a file system served. Custom
+// file systems are left unresolved and assumed not to list
+// directories.
+func resolveFS(pass *analysis.Pass, file *ast.File, fsys ast.Expr) staticRoot {
+	switch e := ast.Unparen(fsys).(type) {
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok || len(e.Args) == 0 {
+			break
+		}
+		switch {
+		case isPkgSelector(pass.TypesInfo, file, sel, "net/http", "Dir"),
+			isPkgSelector(pass.TypesInfo, file, sel, "os", "DirFS"):
+			return staticRoot{dir: exprValue(e.Args[0]), listing: true}
+		case isPkgSelector(pass.TypesInfo, file, sel, ginPath, "Dir"):
+			return staticRoot{dir: exprValue(e.Args[0]), listing: len(e.Args) > 1 && extractIdent(e.Args[1]) == "true"}
+		case isPkgSelector(pass.TypesInfo, file, sel, "net/http", "FS"):
+			root := resolveFS(pass, file, e.Args[0])
+			root.listing = true
+			return root
+		case isPkgSelector(pass.TypesInfo, file, sel, "io/fs", "Sub") && len(e.Args) == 2:
+			root := resolveFS(pass, file, e.Args[0])
+			if root.embed != nil {
+				root.sub = path.Join(root.sub, exprValue(e.Args[1]))
+			} else {
+				root.dir = path.Join(root.dir, exprValue(e.Args[1]))
+			}
+			return root
+		}
+	case *ast.Ident:
+		obj, ok := pass.TypesInfo.Uses[e].(*types.Var)
+		if !ok {
+			break
+		}
+		if patterns := embedPatterns(pass, obj); patterns != nil {
+			return staticRoot{embed: patterns, listing: true}
+		}
+		if v := valueOf(pass, obj); v != nil {
+			return resolveFS(pass, file, v)
+		}
+	}
+	return staticRoot{dir: exprValue(fsys)}
+}
+
+// valueOf returns the expression assigned to v where it is defined.
+func valueOf(pass *analysis.Pass, v *types.Var) ast.Expr {
+	for _, file := range pass.Files {
+		if v.Pos() < file.FileStart || v.Pos() > file.FileEnd {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(file, v.Pos(), v.Pos())
+		for _, n := range path {
+			switch decl := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range decl.Lhs {
+					if ident, ok := lhs.(*ast.Ident); !ok || pass.TypesInfo.Defs[ident] != v {
+						continue
+					}
+					if len(decl.Rhs) == len(decl.Lhs) {
+						return decl.Rhs[i]
+					}
+					if len(decl.Rhs) == 1 && i == 0 {
+						return decl.Rhs[0]
+					}
+				}
+				return nil
+			case *ast.ValueSpec:
+				for i, name := range decl.Names {
+					if pass.TypesInfo.Defs[name] == v && i < len(decl.Values) {
+						return decl.Values[i]
+					}
+				}
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// embedPatterns returns the patterns of the //go:embed directives of
+// the variable v.
+func embedPatterns(pass *analysis.Pass, v *types.Var) (patterns []string) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok || len(vs.Names) != 1 || pass.TypesInfo.Defs[vs.Names[0]] != v {
+					continue
+				}
+				doc := vs.Doc
+				if doc == nil {
+					doc = gen.Doc
+				}
+				if doc == nil {
+					return nil
+				}
+				for _, c := range doc.List {
+					line, ok := strings.CutPrefix(c.Text, "//go:embed ")
+					if !ok {
+						continue
+					}
+					for _, p := range strings.Fields(line) {
+						if s, err := strconv.Unquote(p); err == nil {
+							p = s
+						}
+						patterns = append(patterns, p)
+					}
+				}
+				return patterns
+			}
+		}
+	}
+	return nil
+}
+
+// staticPrefix returns the path of the route serving call: the route
+// whose handler is, wraps or holds call, or the pattern of an
+// enclosing Handle call as with the default ServeMux.
+func staticPrefix(pass *analysis.Pass, file *ast.File, routes *Routes, call *ast.CallExpr) string {
+	path, _ := astutil.PathEnclosingInterval(file, call.Pos(), call.End())
+	var holder types.Object
+	for _, n := range path {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				break
+			}
+			for i, rhs := range node.Rhs {
+				if ident, ok := node.Lhs[i].(*ast.Ident); ok && encloses(rhs, call) {
+					holder = pass.TypesInfo.ObjectOf(ident)
+				}
+			}
+		case *ast.ValueSpec:
+			for i, value := range node.Values {
+				if i < len(node.Names) && encloses(value, call) {
+					holder = pass.TypesInfo.ObjectOf(node.Names[i])
+				}
+			}
+		}
+	}
+	for _, router := range routes.Routers {
+		for _, route := range router.Routes {
+			h, ok := routes.handlers[route]
+			if !ok {
+				continue
+			}
+			if encloses(h, call) || encloses(call, h) {
+				return route.Path
+			}
+			if ident, ok := h.(*ast.Ident); ok && holder != nil && pass.TypesInfo.Uses[ident] == holder {
+				return route.Path
+			}
+		}
+	}
+	for _, n := range path {
+		if c, ok := n.(*ast.CallExpr); ok && c != call && len(c.Args) == 2 && calleeName(c) == "Handle" {
+			return exprValue(c.Args[0])
+		}
+	}
+	return ""
+}
+
+func encloses(outer, inner ast.Node) bool {
+	return outer.Pos() <= inner.Pos() && inner.End() <= outer.End()
+}
+
+// staticFiles lists the files reachable through root, as paths
+// relative to dir, the package directory. Embedded files are resolved
+// from the package directory and directories from it or one of its
+// parents up to the enclosing module, as servers usually run from
+// there.
+func staticFiles(dir string, root staticRoot) (files []string) {
+	if root.embed != nil {
+		for _, pattern := range root.embed {
+			all := strings.HasPrefix(pattern, "all:")
+			matches, _ := filepath.Glob(filepath.Join(dir, strings.TrimPrefix(pattern, "all:")))
+			for _, match := range matches {
+				rel, err := filepath.Rel(dir, match)
+				if err != nil {
+					continue
+				}
+				for _, f := range walkStatic(match, filepath.ToSlash(rel), !all) {
+					if root.sub == "" || strings.HasPrefix(f, root.sub+"/") {
+						files = append(files, f)
+					}
+				}
+			}
+		}
+		return files
+	}
+	if root.dir == "" || filepath.IsAbs(root.dir) || strings.ContainsAny(root.dir, "()+ ") {
+		return nil
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, root.dir)); err == nil {
+			return walkStatic(filepath.Join(d, root.dir), path.Clean(root.dir), false)
+		}
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil || filepath.Dir(d) == d {
+			return nil
+		}
+	}
+}
+
+// walkStatic lists the files under root, named after name, leaving
+// out those starting with . or _ when skipHidden is set. A .git
+// directory is listed alone.
+func walkStatic(root, name string, skipHidden bool) (files []string) {
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || len(files) >= maxStaticFiles {
+			return filepath.SkipAll
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = path.Join(name, filepath.ToSlash(rel))
+		base := d.Name()
+		if p != root && skipHidden && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.IsDir() && base == ".git":
+			files = append(files, rel+"/")
+			return filepath.SkipDir
+		case !d.IsDir():
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files
+}
+
+// checkStaticMount reports what m exposes.
+func checkStaticMount(pass *analysis.Pass, call *ast.CallExpr, m *StaticMount) {
+	on := ""
+	if m.Prefix != "" {
+		on = " on " + m.Prefix
+	}
+	if m.Listing {
+		report(pass, call, checkStaticListing, "directory listing of %s enabled%s", m.Root, on)
+	}
+
+	var sensitive, dotfiles []string
+	for _, f := range m.Files {
+		switch {
+		case isSensitiveStaticFile(f):
+			sensitive = append(sensitive, f)
+		case isDotfile(f):
+			dotfiles = append(dotfiles, f)
+		}
+	}
+	switch {
+	case len(sensitive) > 0:
+		report(pass, call, checkStaticSensitiveRoot, "%s%s exposes source, .git, configuration or template files: %s", m.Root, on, listFiles(sensitive))
+	case !m.Embed && isWorkingDir(m.Root):
+		report(pass, call, checkStaticSensitiveRoot, "%s%s exposes the working directory of the server", m.Root, on)
+	}
+	if len(dotfiles) > 0 {
+		report(pass, call, checkStaticDotfiles, "dotfiles reachable%s: %s", on, listFiles(dotfiles))
+	}
+}
+
+// sensitiveStaticExts are the extensions of source, configuration,
+// key and template files.
+var sensitiveStaticExts = map[string]bool{
+	".go": true, ".mod": true, ".sum": true,
+	".env": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true, ".conf": true,
+	".pem": true, ".key": true,
+	".tmpl": true, ".gohtml": true, ".tpl": true,
+}
+
+func isSensitiveStaticFile(f string) bool {
+	base := path.Base(f)
+	return strings.HasSuffix(f, ".git/") || strings.HasPrefix(base, ".env") || sensitiveStaticExts[path.Ext(base)] ||
+		strings.HasPrefix(f, "templates/") || strings.Contains(f, "/templates/")
+}
+
+func isDotfile(f string) bool {
+	for _, elem := range strings.Split(f, "/") {
+		if strings.HasPrefix(elem, ".") && elem != "." && elem != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func isWorkingDir(root string) bool {
+	switch root {
+	case ".", "./", "/", "..", "../":
+		return true
+	}
+	return false
+}
+
+// listFiles shortens files for messages.
+func listFiles(files []string) string {
+	const max = 5
+	if len(files) <= max {
+		return strings.Join(files, ", ")
+	}
+	return strings.Join(files[:max], ", ") + " and " + strconv.Itoa(len(files)-max) + " more"
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"cmp"
+	"crypto/tls"
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/spf13/viper"
+)
+
+var addr = flag.String("addr", ":8080", "listen address")
+
+var debug bool
+
+type spec struct {
+	Database string `envconfig:"DATABASE_URL"`
+	Timeout  int    `default:"30"`
+	internal string
+}
+
+func getenv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
+func main() {
+	flag.BoolVar(&debug, "debug", false, "debug mode")
+	flag.Parse()
+
+	var s spec
+	envconfig.Process("app", &s)
+
+	viper.SetConfigFile("/etc/app/config.yaml")
+	viper.SetDefault("cors.origin", "*")
+	viper.ReadInConfig()
+
+	cert := os.Getenv("TLS_CERT")
+	if cert == "" {
+		cert = "server.crt"
+	}
+	key := cmp.Or(os.Getenv("TLS_KEY"), "server.key")
+	secret := getenv("SESSION_SECRET", "")
+	level := getenv("LOG_LEVEL", "info")
+	_, _ = secret, level
+
+	srv := &http.Server{
+		Addr:      *addr,
+		TLSConfig: &tls.Config{ServerName: os.Getenv("SERVER_NAME")},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", viper.GetString("cors.origin"))
+		}),
+	}
+	srv.ListenAndServeTLS(cert, key)
+}
@@ -0,0 +1,63 @@
+package csrf
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/csrf"
+	"github.com/gorilla/mux"
+	"github.com/justinas/nosurf"
+)
+
+func routes() {
+	m := http.NewServeMux()
+	m.HandleFunc("POST /transfer", transfer) // want `POST /transfer accepts cookie-authenticated requests without CSRF protection`
+	m.HandleFunc("GET /balance", balance)
+	m.HandleFunc("/profile", profile) // want `POST /profile accepts cookie-authenticated requests without CSRF protection`
+	m.HandleFunc("POST /api/settings", settings) // want `POST /api/settings decodes JSON without checking the Content-Type`
+	m.HandleFunc("POST /api/strict", strict)
+	m.Handle("DELETE /items", nosurf.New(http.HandlerFunc(transfer)))
+	m.Handle("PUT /items", verifyOrigin(http.HandlerFunc(transfer)))
+
+	r := mux.NewRouter()
+	r.Use(csrf.Protect([]byte("32-byte-long-auth-key")))
+	r.HandleFunc("/form", transfer).Methods("POST")
+}
+
+func session(r *http.Request) string {
+	c, err := r.Cookie("session")
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func transfer(w http.ResponseWriter, r *http.Request) {
+	_ = session(r)
+	_ = r.FormValue("amount")
+}
+
+func balance(w http.ResponseWriter, r *http.Request) {}
+
+func profile(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		_ = r.PostFormValue("name")
+	}
+}
+
+func settings(w http.ResponseWriter, r *http.Request) {
+	var s map[string]string
+	json.NewDecoder(r.Body).Decode(&s)
+}
+
+func strict(w http.ResponseWriter, r *http.Request) {
+	if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != "application/json" {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	var s map[string]string
+	json.NewDecoder(r.Body).Decode(&s)
+}
+
+func verifyOrigin(next http.Handler) http.Handler { return next }
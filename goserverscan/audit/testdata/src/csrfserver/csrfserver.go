@@ -0,0 +1,17 @@
+package csrfserver
+
+import (
+	"net/http"
+
+	"github.com/gorilla/csrf"
+)
+
+func main() {
+	m := http.NewServeMux()
+	m.HandleFunc("POST /transfer", transfer)
+	http.ListenAndServe(":8080", csrf.Protect([]byte("32-byte-long-auth-key"))(m))
+}
+
+func transfer(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "session"})
+}
@@ -0,0 +1,5 @@
+// Package legacy is a module without symbol level vulnerability data
+// for tests.
+package legacy
+
+func Do() {}
@@ -0,0 +1,20 @@
+// Package parser is a vulnerable package for tests.
+package parser
+
+type Decoder struct{}
+
+func NewDecoder() *Decoder { return &Decoder{} }
+
+func (d *Decoder) Decode(s string) error { return nil }
+
+func (d *Decoder) Reset() {}
+
+func Parse(s string) error { return nil }
+
+func Safe(s string) error { return nil }
+
+type Tokenizer struct{}
+
+func NewTokenizer() *Tokenizer { return &Tokenizer{} }
+
+func (t *Tokenizer) Decode(s string) error { return nil }
@@ -0,0 +1,4 @@
+// Package util is a package of a vulnerable module for tests.
+package util
+
+func Trim(s string) string { return s }
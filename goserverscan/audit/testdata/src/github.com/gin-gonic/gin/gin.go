@@ -0,0 +1,19 @@
+package gin
+
+import "net/http"
+
+type RouterGroup struct{}
+
+type Engine struct {
+	RouterGroup
+}
+
+func Default() *Engine { return &Engine{} }
+
+func (g *RouterGroup) Static(relativePath, root string) {}
+
+func (g *RouterGroup) StaticFS(relativePath string, fs http.FileSystem) {}
+
+func (g *RouterGroup) StaticFile(relativePath, filepath string) {}
+
+func Dir(root string, listDirectory bool) http.FileSystem { return http.Dir(root) }
@@ -0,0 +1,11 @@
+// Package httprate is a stub of github.com/go-chi/httprate for tests.
+package httprate
+
+import (
+	"net/http"
+	"time"
+)
+
+func LimitByIP(requestLimit int, windowLength time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler { return next }
+}
@@ -0,0 +1,9 @@
+package csrf
+
+import "net/http"
+
+type Option func()
+
+func Protect(authKey []byte, opts ...Option) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler { return h }
+}
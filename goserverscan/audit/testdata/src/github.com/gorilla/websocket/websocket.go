@@ -0,0 +1,20 @@
+// Package websocket is a stub of github.com/gorilla/websocket for tests.
+package websocket
+
+import "net/http"
+
+type Upgrader struct {
+	ReadBufferSize  int
+	WriteBufferSize int
+	CheckOrigin     func(r *http.Request) bool
+}
+
+type Conn struct{}
+
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, h http.Header) (*Conn, error) {
+	return &Conn{}, nil
+}
+
+func (c *Conn) SetReadLimit(limit int64) {}
+
+func (c *Conn) ReadMessage() (int, []byte, error) { return 0, nil, nil }
@@ -0,0 +1,6 @@
+// Package graphql is a stub of github.com/graph-gophers/graphql-go for tests.
+package graphql
+
+type Schema struct{}
+
+func MustParseSchema(schemaString string, resolver interface{}) *Schema { return &Schema{} }
@@ -0,0 +1,19 @@
+package echo
+
+import "io/fs"
+
+type Echo struct{}
+
+type Group struct{}
+
+func New() *Echo { return &Echo{} }
+
+func (e *Echo) Static(prefix, root string) {}
+
+func (e *Echo) StaticFS(pathPrefix string, filesystem fs.FS) {}
+
+func (e *Echo) File(path, file string) {}
+
+func (e *Echo) Group(prefix string) *Group { return &Group{} }
+
+func (g *Group) Static(prefix, root string) {}
@@ -0,0 +1,20 @@
+// Package viper is a stub of github.com/spf13/viper for tests.
+package viper
+
+type Viper struct{}
+
+func New() *Viper { return &Viper{} }
+
+func (v *Viper) GetString(key string) string { return "" }
+
+func SetDefault(key string, value interface{}) {}
+
+func SetConfigFile(in string) {}
+
+func ReadInConfig() error { return nil }
+
+func GetString(key string) string { return "" }
+
+func GetStringSlice(key string) []string { return nil }
+
+func GetBool(key string) bool { return false }
@@ -0,0 +1,26 @@
+// Package grpc is a stub of google.golang.org/grpc for tests.
+package grpc
+
+import "context"
+
+type Server struct{}
+
+type ServerOption interface{}
+
+type UnaryServerInfo struct{ FullMethod string }
+
+type UnaryHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+type UnaryServerInterceptor func(ctx context.Context, req interface{}, info *UnaryServerInfo, handler UnaryHandler) (interface{}, error)
+
+type StreamServerInterceptor func(srv interface{}, ss interface{}, info interface{}, handler interface{}) error
+
+func NewServer(opt ...ServerOption) *Server { return &Server{} }
+
+func UnaryInterceptor(i UnaryServerInterceptor) ServerOption { return nil }
+
+func StreamInterceptor(i StreamServerInterceptor) ServerOption { return nil }
+
+func ChainUnaryInterceptor(interceptors ...UnaryServerInterceptor) ServerOption { return nil }
+
+func ChainStreamInterceptor(interceptors ...StreamServerInterceptor) ServerOption { return nil }
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+)
+
+type api struct{}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+type files struct{}
+
+func (files) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+type admin struct {
+	http.Handler
+}
+
+type server struct{}
+
+func (s *server) handleIndex() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
+func newHealth() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+}
+
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func main() {
+	s := &server{}
+	m := http.NewServeMux()
+	m.Handle("/api/", http.StripPrefix("/api", &api{}))
+	m.Handle("/static/", http.TimeoutHandler(files{}, time.Second, "timeout"))
+	m.Handle("/", s.handleIndex())
+	m.Handle("/health", newHealth())
+	m.Handle("/admin", requireAuth(admin{}))
+
+	http.ListenAndServe(":8080", m)
+	srv := &http.Server{Addr: ":8443", Handler: requireAuth(&api{})}
+	srv.ListenAndServeTLS("cert.pem", "key.pem")
+}
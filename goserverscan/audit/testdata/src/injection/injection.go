@@ -0,0 +1,67 @@
+package injection
+
+import (
+	"database/sql"
+	htmltemplate "html/template"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"run":   exec.Command, // want "template function run exposes exec.Command to templates"
+	"cat": func(name string) string { // want "template function cat exposes os.ReadFile to templates"
+		b, _ := os.ReadFile(name)
+		return string(b)
+	},
+}
+
+var views = template.Must(template.New("views").Funcs(funcs).Parse("{{upper .}}"))
+
+type actions struct{}
+
+func (actions) List() {}
+
+func routes(db *sql.DB) {
+	m := http.NewServeMux()
+	m.HandleFunc("/preview", preview)
+	m.HandleFunc("/action", action)
+	m.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		page(w, db, r.URL.Query().Get("id"))
+	})
+}
+
+func preview(w http.ResponseWriter, r *http.Request) {
+	body := r.FormValue("template")
+	t, err := template.New("preview").Parse(body) // want "template parsed from request data on /preview"
+	if err != nil {
+		return
+	}
+	t.Execute(w, nil)
+
+	safe := htmltemplate.Must(htmltemplate.New("safe").Parse("<p>{{.}}</p>"))
+	safe.Execute(w, body)
+}
+
+func page(w http.ResponseWriter, db *sql.DB, id string) {
+	var content string
+	if err := db.QueryRow("SELECT content FROM pages WHERE id = ?", id).Scan(&content); err != nil {
+		return
+	}
+	t := htmltemplate.New("page").Funcs(htmltemplate.FuncMap{
+		"env": os.Getenv,
+		"rm":  os.RemoveAll, // want "template function rm exposes os.RemoveAll to templates"
+	})
+	t.Parse(content) // want "template parsed from database data"
+	t.Execute(w, nil)
+}
+
+func action(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("do")
+	reflect.ValueOf(actions{}).MethodByName(name).Call(nil) // want "method called by reflection is chosen from request data on /action"
+	reflect.ValueOf(actions{}).MethodByName("List").Call(nil)
+}
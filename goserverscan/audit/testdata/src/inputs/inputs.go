@@ -0,0 +1,52 @@
+package inputs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Remember bool   `json:"remember,omitempty"`
+	Scopes   []string
+	internal string
+	Ignored  string `json:"-"`
+}
+
+func routes() {
+	m := http.NewServeMux()
+	m.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		_ = r.PathValue("id") + q.Get("sort") + r.URL.Query().Get("page") + r.FormValue("filter")
+		_ = r.Header.Get("X-Request-ID")
+		if c, err := r.Cookie("session"); err == nil {
+			_ = c
+		}
+	})
+	m.HandleFunc("POST /login", login)
+	m.HandleFunc("POST /upload", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.FormValue("name") + r.PostForm.Get("csrf_token")
+	})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/users/{user:[0-9]+}/files/{path}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		_ = vars["user"] + mux.Vars(r)["path"]
+	})
+	r.HandleFunc("/raw", func(w http.ResponseWriter, r *http.Request) {
+		var creds credentials
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &creds)
+	})
+}
+
+func login(w http.ResponseWriter, r *http.Request) {
+	var creds credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		return
+	}
+}
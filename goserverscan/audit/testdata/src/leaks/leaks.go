@@ -0,0 +1,66 @@
+package leaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+)
+
+type credentials struct {
+	Username string
+	Password string
+}
+
+func login(w http.ResponseWriter, r *http.Request) {
+	var c credentials
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("login attempt for %s with %s", c.Username, c.Password) // want "Password written to logs"
+	log.Printf("password length %d", len(c.Password))
+	slog.Info("login", "user", c.Username, "authorization", r.Header.Get("Authorization")) // want "authorization written to logs"
+
+	token, err := issue(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError) // want "internal error details written to the HTTP response"
+		return
+	}
+	slog.Debug("issued", "token", token) // want "token written to logs"
+	fmt.Fprintf(w, "%s", token)
+}
+
+func debug(w http.ResponseWriter, r *http.Request) {
+	dump, err := httputil.DumpRequest(r, true) // want "whole request dumped on /debug"
+	if err != nil {
+		fmt.Fprintf(w, "cannot dump: %v", err) // want "internal error details written to the HTTP response"
+		return
+	}
+	log.Println(string(dump))
+	log.Printf("%+v", r) // want "whole request logged on /debug"
+}
+
+func save(w http.ResponseWriter, r *http.Request) {
+	if err := store(); err != nil {
+		log.Printf("store: %v", err)
+		http.Error(w, "internal error", 500)
+		return
+	}
+	if err := store(); err != nil {
+		w.Write([]byte(err.Error())) // want "internal error details written to the HTTP response"
+	}
+}
+
+func issue(c credentials) (string, error) { return "", nil }
+
+func store() error { return nil }
+
+func routes() {
+	m := http.NewServeMux()
+	m.HandleFunc("/login", login)
+	m.HandleFunc("/debug", debug)
+	m.HandleFunc("/save", save)
+}
@@ -0,0 +1,94 @@
+package races
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	visits   = make(map[string]int)
+	recent   []string
+	mu       sync.Mutex
+	sessions sync.Map
+	total    atomic.Int64
+)
+
+type server struct {
+	mu    sync.RWMutex
+	cache map[string]string
+	hits  int
+}
+
+func index(w http.ResponseWriter, r *http.Request) {
+	visits[r.URL.Path]++                // want `package variable visits shared between requests is written by handler on /, /home without synchronization`
+	recent = append(recent, r.URL.Path) // want `package variable recent shared between requests is written by handler on /, /home without synchronization`
+	sessions.Store(r.URL.Path, true)
+	total.Add(1)
+}
+
+func locked(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	defer mu.Unlock()
+	visits[r.URL.Path]++
+}
+
+func unlocked(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	visits[r.URL.Path]++
+	mu.Unlock()
+	recent = nil // want `package variable recent shared between requests is written by handler on /unlocked without synchronization`
+	if r.Method == "POST" {
+		mu.Lock()
+		delete(visits, r.URL.Path)
+		mu.Unlock()
+	}
+	delete(visits, "/") // want `package variable visits shared between requests is written by handler on /unlocked without synchronization`
+}
+
+type pool struct{}
+
+func (pool) Do(f func()) { go f() }
+
+var workers pool
+
+func fetch(w http.ResponseWriter, r *http.Request) {
+	workers.Do(func() {
+		visits[r.URL.Path]++ // want `package variable visits shared between requests is written by handler on /fetch without synchronization`
+	})
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.cache[r.URL.Path] = "x" // want `field s.cache shared between requests is written by handler on /get without synchronization`
+	s.hits++                  // want `field s.hits shared between requests is written by handler on /get without synchronization`
+}
+
+func (s *server) handleCount() http.HandlerFunc {
+	var count int
+	return func(w http.ResponseWriter, r *http.Request) {
+		count++ // want `captured variable count shared between requests is written by handler on /count without synchronization`
+		n := 0
+		n++
+	}
+}
+
+func (s *server) routes() {
+	var once sync.Once
+	last := ""
+	m := http.NewServeMux()
+	m.HandleFunc("/", index)
+	m.HandleFunc("/home", index)
+	m.HandleFunc("/locked", locked)
+	m.HandleFunc("/unlocked", unlocked)
+	m.HandleFunc("/fetch", fetch)
+	m.HandleFunc("/get", s.get)
+	m.HandleFunc("/count", s.handleCount())
+	m.HandleFunc("/last", func(w http.ResponseWriter, r *http.Request) {
+		last = r.URL.Path // want `captured variable last shared between requests is written by handler on /last without synchronization`
+		once.Do(func() {
+			delete(visits, "/")
+		})
+	})
+}
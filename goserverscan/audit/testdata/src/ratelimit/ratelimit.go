@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/httprate"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+)
+
+var limiter = rate.NewLimiter(1, 5)
+
+func limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !limiter.Allow() {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func slowDown(next http.Handler) http.Handler { return next }
+
+func routes() {
+	m := http.NewServeMux()
+	m.HandleFunc("POST /login", login)                                               // want "POST /login authenticates users without rate limiting, allowing brute force"
+	m.HandleFunc("/password/reset", func(w http.ResponseWriter, r *http.Request) {}) // want "/password/reset authenticates users without rate limiting, allowing brute force"
+	m.HandleFunc("/session", checkPassword)                                          // want "/session authenticates users without rate limiting, allowing brute force"
+	m.Handle("/signin", limit(http.HandlerFunc(login)))
+	m.Handle("/otp", httprate.LimitByIP(5, time.Minute)(http.HandlerFunc(login)))
+	m.Handle("/token", slowDown(http.HandlerFunc(login)))
+	m.HandleFunc("/api/token", limitedToken)
+	m.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	r := mux.NewRouter()
+	r.Use(limit)
+	r.HandleFunc("/login", login)
+}
+
+func login(w http.ResponseWriter, r *http.Request) {}
+
+func checkPassword(w http.ResponseWriter, r *http.Request) {
+	bcrypt.CompareHashAndPassword(nil, []byte(r.FormValue("password")))
+}
+
+func limitedToken(w http.ResponseWriter, r *http.Request) {
+	if !limiter.Allow() {
+		return
+	}
+}
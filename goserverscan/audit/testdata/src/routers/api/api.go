@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var Default = mux.NewRouter() // want Default:"router\\(gorilla/mux\\)"
+
+func init() {
+	Default.HandleFunc("/default", list)
+}
+
+func NewRouter() *mux.Router { // want NewRouter:"router\\(gorilla/mux\\)"
+	r := mux.NewRouter()
+	r.HandleFunc("/api/items", list).Methods("GET")
+	return r
+}
+
+func Register(r *mux.Router) { // want Register:"registersRoutes"
+	r.HandleFunc("/api/users", list)
+}
+
+func NewVersioned() (*mux.Router, error) { // want NewVersioned:"router\\(gorilla/mux\\)"
+	r := mux.NewRouter()
+	r.HandleFunc("/v1", list)
+	return r, nil
+}
+
+func list(w http.ResponseWriter, r *http.Request) {}
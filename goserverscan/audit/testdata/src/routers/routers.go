@@ -0,0 +1,98 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"routers/api"
+)
+
+type server struct {
+	router *mux.Router
+	admin  *http.ServeMux
+}
+
+func newServer() *server {
+	s := &server{admin: http.NewServeMux()}
+	s.router = mux.NewRouter()
+	s.routes()
+	return s
+}
+
+func (s *server) routes() {
+	s.router.Use(logging)
+	s.router.HandleFunc("/", s.index)
+	registerHealth(s.router)
+	s.admin.HandleFunc("/admin/stats", s.index)
+}
+
+func registerHealth(r *mux.Router) { // want registerHealth:"registersRoutes"
+	r.HandleFunc("/health", health)
+	registerPing(r)
+}
+
+func registerPing(r *mux.Router) { // want registerPing:"registersRoutes"
+	r.HandleFunc("/ping", health)
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) {}
+
+var public = http.NewServeMux() // want public:"router\\(net/http\\)"
+
+func init() {
+	public.HandleFunc("/public", health)
+}
+
+func more() {
+	public.HandleFunc("/more", health)
+}
+
+func newAPI() *mux.Router { // want newAPI:"router\\(gorilla/mux\\)"
+	r := api.NewRouter()
+	r.HandleFunc("/api/local", health)
+	api.Register(r)
+	return r
+}
+
+func start() {
+	r := newAPI()
+	r.HandleFunc("/api/extra", health)
+	api.Default.HandleFunc("/default/extra", health)
+}
+
+func newChecked() (*mux.Router, error) { // want newChecked:"router\\(gorilla/mux\\)"
+	r := mux.NewRouter()
+	r.HandleFunc("/checked", health)
+	return r, nil
+}
+
+func startChecked() error {
+	r, err := newChecked()
+	if err != nil {
+		return err
+	}
+	r.HandleFunc("/checked/extra", health)
+	v, err := api.NewVersioned()
+	if err != nil {
+		return err
+	}
+	v.HandleFunc("/v1/extra", health)
+	return nil
+}
+
+func startAll() {
+	routers := map[string]*mux.Router{}
+	routers["api"] = mux.NewRouter()
+	routers["web"] = mux.NewRouter()
+	routers["api"].HandleFunc("/map/api", health)
+	routers["web"].HandleFunc("/map/web", health)
+
+	var muxes []*http.ServeMux
+	muxes = append(muxes, http.NewServeMux())
+	muxes[0].HandleFunc("/slice", health)
+}
+
+func logging(next http.Handler) http.Handler { return next }
+
+func health(w http.ResponseWriter, r *http.Request) {}
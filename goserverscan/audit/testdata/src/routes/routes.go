@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var Router = mux.NewRouter() // want Router:"router\\(gorilla/mux\\)"
+
+func NewAPI() *http.ServeMux { // want NewAPI:"router\\(net/http\\)"
+	return http.NewServeMux()
+}
+
+func newRouterInstances() {
+	r := mux.NewRouter()
+	r.Handle("/new/gorillamux/handle", nil)
+	r.HandleFunc("/new/gorillamux/handlefunc", nil)
+
+	m := http.NewServeMux()
+	m.Handle("/new/httpmux/handle", nil)
+	m.HandleFunc("/new/httpmux/handlefunc", nil)
+}
+
+func routerAsArguments(r *mux.Router, m *http.ServeMux) { // want routerAsArguments:"registersRoutes"
+	r.Handle("/arg/gorillamux/handle", nil)
+	r.HandleFunc("/arg/gorillamux/handlefunc", nil)
+	m.Handle("/arg/httpmux/handle", nil)
+	m.HandleFunc("/arg/httpmux/handlefunc", nil)
+}
+
+func requireAuth(next http.Handler) http.Handler { // want requireAuth:"authMiddleware"
+	return next
+}
+
+func checkKey(next http.Handler) http.Handler { // want checkKey:"authMiddleware"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func logging(next http.Handler) http.Handler {
+	return next
+}
+
+func trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("trace"); err == nil {
+			w.Header().Set("X-Trace", c.Value)
+		}
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func basic(next http.Handler) http.Handler { // want basic:"authMiddleware"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user+pass == "" {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func oauthScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler { return next }
+}
+
+func list(w http.ResponseWriter, r *http.Request) {}
+
+func middleware() {
+	r := mux.NewRouter()
+	r.Use(logging)
+	r.Handle("/public", logging(http.HandlerFunc(list)))
+	r.Handle("/admin", checkKey(http.HandlerFunc(list))).Methods("POST", "PUT")
+
+	m := http.NewServeMux()
+	m.Handle("DELETE /items/{id}", requireAuth(http.HandlerFunc(list)))
+	m.Handle("/traced", trace(http.HandlerFunc(list)))
+	m.Handle("/basic", basic(http.HandlerFunc(list)))
+
+	s := mux.NewRouter()
+	s.Use(oauthScope("admin"))
+	s.Handle("/scoped", http.HandlerFunc(list))
+}
@@ -0,0 +1,37 @@
+package rules
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"net/http"
+)
+
+var db *sql.DB
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	query := "SELECT * FROM users WHERE name = '" + name + "'"
+	db.Query(query)                 // want "SQL query built from request data" "database query"
+	db.Query("SELECT * FROM users") // want "database query"
+	md5.New()                       // want "MD5 is not collision resistant"
+}
+
+// Package level calls are outside of any handler.
+var query = "SELECT * FROM users WHERE name = '" + defaultName + "'"
+
+var rows, _ = db.Query(query) // want "database query"
+
+const defaultName = "admin"
+
+func hashes() {
+	md5.New() //auditools:ignore weak-hash legacy checksums only
+	//auditools:ignore weak-hash used for cache keys
+	md5.New()
+	//auditools:ignore other-rule
+	md5.New() // want "MD5 is not collision resistant"
+}
+
+func main() {
+	http.ListenAndServe(":8080", nil) // want "server listens on all interfaces"
+	http.ListenAndServe("127.0.0.1:8080", nil)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"google.golang.org/grpc"
+)
+
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) SayHello(ctx context.Context, in string) (string, error) {
+	return "", nil
+}
+
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+
+func RegisterGreeterServer(s *grpc.Server, srv interface{}) {}
+
+type greeter struct {
+	UnimplementedGreeterServer
+}
+
+func (g *greeter) SayHello(ctx context.Context, in string) (string, error) { return in, nil }
+
+func (g *greeter) SayGoodbye(ctx context.Context, in string) (string, error) { return in, nil }
+
+func logRequests(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+	return h(ctx, req)
+}
+
+func checkToken(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+	return h(ctx, req)
+}
+
+func publicGRPC() {
+	s := grpc.NewServer(grpc.UnaryInterceptor(logRequests)) // want "gRPC server without authentication interceptor exposes 2 RPCs"
+	RegisterGreeterServer(s, &greeter{})
+}
+
+func privateGRPC() {
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(logRequests)}
+	opts = append(opts, grpc.UnaryInterceptor(checkToken))
+	s := grpc.NewServer(opts...)
+	RegisterGreeterServer(s, &greeter{})
+}
+
+func RegisterAdminServer(s *grpc.Server, srv interface{}) {}
+
+type admin struct{}
+
+func (a *admin) Shutdown(ctx context.Context, in string) (string, error) { return in, nil }
+
+type servers struct {
+	public, admin *grpc.Server
+}
+
+func (s *servers) start() {
+	s.public = grpc.NewServer() // want "gRPC server without authentication interceptor exposes 2 RPCs"
+	s.admin = grpc.NewServer(grpc.UnaryInterceptor(checkToken))
+	RegisterGreeterServer(s.public, &greeter{})
+	RegisterAdminServer(s.admin, &admin{})
+}
+
+type QueryResolver interface {
+	Users(ctx context.Context) ([]string, error)
+	User(ctx context.Context, id string) (string, error)
+}
+
+type Resolver struct{}
+
+type queryResolver struct{ *Resolver }
+
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+func (r *queryResolver) Users(ctx context.Context) ([]string, error) { return nil, nil }
+
+func (r *queryResolver) User(ctx context.Context, id string) (string, error) { return "", nil }
+
+type Config struct {
+	Resolvers *Resolver
+}
+
+func NewExecutableSchema(cfg Config) interface{} { return nil }
+
+func gqlgen() {
+	srv := handler.NewDefaultServer(NewExecutableSchema(Config{Resolvers: &Resolver{}})) // want "GraphQL server mounted on /query without authentication middleware exposes 2 resolvers"
+	m := http.NewServeMux()
+	m.Handle("/query", srv)
+}
+
+type rootResolver struct{}
+
+func (r *rootResolver) Hello() string { return "world" }
+
+func requireAuth(next http.Handler) http.Handler { return next }
+
+func gophers() {
+	schema := graphql.MustParseSchema("type Query { hello: String! }", &rootResolver{})
+	m := http.NewServeMux()
+	m.Handle("/graphql", requireAuth(&relay.Handler{Schema: schema}))
+}
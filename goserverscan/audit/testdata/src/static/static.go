@@ -0,0 +1,46 @@
+package static
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed assets
+var assets embed.FS
+
+//go:embed all:assets
+var allAssets embed.FS
+
+func routes() {
+	m := http.NewServeMux()
+	m.Handle("/public/", http.StripPrefix("/public/", http.FileServer(http.Dir("public")))) // want `directory listing of public enabled on /public/` `dotfiles reachable on /public/: public/.htpasswd`
+	m.Handle("/", http.FileServer(http.Dir("."))) // want `directory listing of . enabled on /` `. on / exposes source, .git, configuration or template files: static.go, templates/index.tmpl` `dotfiles reachable on /: assets/.hidden, public/.htpasswd`
+
+	sub, _ := fs.Sub(assets, "assets")
+	m.Handle("/assets/", http.FileServerFS(sub)) // want `directory listing of assets enabled on /assets/`
+	m.Handle("/all/", http.FileServer(http.FS(allAssets))) // want `directory listing of all:assets enabled on /all/` `dotfiles reachable on /all/: assets/.hidden`
+
+	views := http.FileServer(http.Dir("templates")) // want `directory listing of templates enabled on /views/` `templates on /views/ exposes source, .git, configuration or template files: templates/index.tmpl`
+	m.Handle("/views/", views)
+
+	m.Handle("/custom/", http.FileServer(noListing{http.Dir("public")}))
+}
+
+type noListing struct {
+	fs http.FileSystem
+}
+
+func (n noListing) Open(name string) (http.File, error) { return n.fs.Open(name) }
+
+func frameworks() {
+	r := gin.Default()
+	r.Static("/static", "./public/css")
+	r.StaticFS("/browse", gin.Dir("public", true)) // want `directory listing of public enabled on /browse` `dotfiles reachable on /browse: public/.htpasswd`
+
+	e := echo.New()
+	e.Static("/site", "assets") // want `dotfiles reachable on /site: assets/.hidden`
+}
@@ -0,0 +1,64 @@
+package tokens
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/sessions"
+)
+
+var secret = []byte("s3cr3t")
+
+var store = sessions.NewCookieStore([]byte("cookie-secret")) // want "session store key is hard-coded"
+
+var files = sessions.NewFilesystemStore("/tmp") // want "session store created without authentication key"
+
+var envStore = sessions.NewCookieStore([]byte(os.Getenv("SESSION_KEY")))
+
+func unchecked(w http.ResponseWriter, r *http.Request) {
+	jwt.Parse(r.Header.Get("Authorization"), func(t *jwt.Token) (interface{}, error) { // want "JWT keyfunc does not check token.Method, allowing algorithm confusion on /unchecked"
+		return []byte("hard-coded"), nil // want "JWT verification key is hard-coded"
+	})
+}
+
+func checked(w http.ResponseWriter, r *http.Request) {
+	jwt.Parse(r.Header.Get("Authorization"), keyfunc)
+}
+
+func keyfunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errors.New("unexpected signing method")
+	}
+	return []byte(os.Getenv("JWT_KEY")), nil
+}
+
+func options(w http.ResponseWriter, r *http.Request) {
+	jwt.Parse(r.Header.Get("Authorization"), func(t *jwt.Token) (interface{}, error) {
+		return secret, nil // want "JWT verification key is hard-coded"
+	}, jwt.WithValidMethods([]string{"HS256"}))
+}
+
+func unverified(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	jwt.NewParser().ParseUnverified(token, jwt.MapClaims{}) // want "JWT from the request parsed without signature verification on /unverified"
+}
+
+func sign(w http.ResponseWriter, r *http.Request) {
+	jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{}).SignedString(secret)                          // want "JWT signing key is hard-coded"
+	jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{}).SignedString(jwt.UnsafeAllowNoneSignatureType) // want "JWT none algorithm accepted"
+}
+
+func routes() {
+	m := http.NewServeMux()
+	m.HandleFunc("/unchecked", unchecked)
+	m.HandleFunc("/checked", checked)
+	m.HandleFunc("/options", options)
+	m.HandleFunc("/unverified", unverified)
+	m.HandleFunc("/sign", sign)
+	m.HandleFunc("/none", func(w http.ResponseWriter, r *http.Request) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{}) // want "JWT none algorithm accepted"
+		token.SignedString(jwt.UnsafeAllowNoneSignatureType)               // want "JWT none algorithm accepted"
+	})
+}
@@ -0,0 +1,8 @@
+module example.com/app
+
+go 1.21
+
+require (
+	example.com/fixed v1.5.0
+	example.com/vulnerable v1.2.0
+)
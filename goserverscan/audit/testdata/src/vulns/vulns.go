@@ -0,0 +1,20 @@
+package vulns
+
+import (
+	"example.com/legacy" // want `GO-2024-0003 in example.com/legacy@v0.3.0, fixed in v0.4.0: Legacy issue \(example.com/legacy imported, affected symbols unknown\)`
+	"example.com/vulnerable/parser"
+	"example.com/vulnerable/util"
+)
+
+func handle(s string) {
+	parser.Safe(s)
+	parser.Parse(s) // want `GO-2024-0001 in example.com/vulnerable@v1.2.0, fixed in v1.3.0: Parser panics on crafted input \(Parse called\)`
+	d := parser.NewDecoder()
+	d.Reset()
+	d.Decode(s) // want `GO-2024-0002 in example.com/vulnerable@v1.2.0: Decoder loops forever \(Decoder.Decode called\)`
+	d.Decode(s) // want `GO-2024-0002 in example.com/vulnerable@v1.2.0: Decoder loops forever \(Decoder.Decode called\)`
+	t := parser.NewTokenizer()
+	t.Decode(s) // want `GO-2024-0002 in example.com/vulnerable@v1.2.0: Decoder loops forever \(Tokenizer.Decode called\)`
+	util.Trim(s)
+	legacy.Do()
+}
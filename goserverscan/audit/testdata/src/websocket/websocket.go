@@ -0,0 +1,57 @@
+package websocket
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	nhooyr "nhooyr.io/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true }, // want "CheckOrigin accepts any origin"
+}
+
+var strict = websocket.Upgrader{
+	CheckOrigin: sameOrigin,
+}
+
+func sameOrigin(r *http.Request) bool {
+	if r.Header.Get("Origin") != "https://example.com" {
+		return false
+	}
+	return true
+}
+
+func echo(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil) // want "WebSocket connection on /echo has no read limit"
+	if err != nil {
+		return
+	}
+	conn.ReadMessage()
+}
+
+func chat(w http.ResponseWriter, r *http.Request) {
+	conn, err := strict.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(4096)
+}
+
+func feed(w http.ResponseWriter, r *http.Request) {
+	c, err := nhooyr.Accept(w, r, &nhooyr.AcceptOptions{
+		InsecureSkipVerify: true, // want "InsecureSkipVerify disables the origin check"
+		OriginPatterns:     []string{"*"}, // want "OriginPatterns accepts any origin"
+	})
+	if err != nil {
+		return
+	}
+	c.SetReadLimit(-1) // want "WebSocket connection on /feed read limit is disabled"
+}
+
+func routes() {
+	m := http.NewServeMux()
+	m.HandleFunc("/echo", echo)
+	m.HandleFunc("/chat", chat)
+	m.HandleFunc("/feed", feed)
+}
@@ -0,0 +1,279 @@
+package audit
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// TokensAnalyzer reports misuse of golang-jwt tokens and gorilla
+// sessions: unchecked signing algorithms, unverified parsing, none
+// algorithm and hard-coded keys.
+var TokensAnalyzer = &analysis.Analyzer{
+	Name:             "tokens",
+	Doc:              "report JWT and session handling misuse",
+	Run:              runTokens,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var (
+	checkJWTUncheckedAlg = RegisterCheck(&Check{
+		ID:         "jwt-unchecked-alg",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-347",
+	})
+	checkJWTUnverified = RegisterCheck(&Check{
+		ID:         "jwt-parse-unverified",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-345",
+	})
+	checkJWTNoneAlg = RegisterCheck(&Check{
+		ID:         "jwt-none-alg",
+		Severity:   SeverityCritical,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-347",
+	})
+	checkJWTHardcodedKey = RegisterCheck(&Check{
+		ID:         "jwt-hardcoded-key",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-798",
+	})
+	checkSessionKey = RegisterCheck(&Check{
+		ID:         "session-constant-key",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-798",
+	})
+)
+
+var jwtPaths = []string{
+	"github.com/golang-jwt/jwt",
+	"github.com/golang-jwt/jwt/v4",
+	"github.com/golang-jwt/jwt/v5",
+	"github.com/dgrijalva/jwt-go",
+}
+
+const gorillaSessionsPath = "github.com/gorilla/sessions"
+
+func runTokens(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	for _, file := range pass.Files {
+		var src sources
+		// The none algorithm is reported once per innermost statement,
+		// as jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType).
+		var stmt, reportedIn ast.Stmt
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.FuncDecl:
+				src = requestSources(node)
+			case ast.Stmt:
+				stmt = node
+			case *ast.SelectorExpr:
+				if isAnyPkgSelector(pass, file, node, jwtPaths, "SigningMethodNone") || isAnyPkgSelector(pass, file, node, jwtPaths, "UnsafeAllowNoneSignatureType") {
+					if stmt != nil && node.Pos() >= stmt.Pos() && node.End() <= stmt.End() {
+						if reportedIn == stmt {
+							break
+						}
+						reportedIn = stmt
+					}
+					report(pass, node, checkJWTNoneAlg, "JWT none algorithm accepted, tokens are not signed")
+				}
+			case *ast.CallExpr:
+				checkJWTCall(pass, file, routes, src, node)
+				checkSessionStore(pass, file, node)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+func checkJWTCall(pass *analysis.Pass, file *ast.File, routes *Routes, src sources, call *ast.CallExpr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !importsAnyPath(file, jwtPaths) {
+		return
+	}
+
+	switch sel.Sel.Name {
+	case "Parse", "ParseWithClaims":
+		keyIndex := 1
+		if sel.Sel.Name == "ParseWithClaims" {
+			keyIndex = 2
+		}
+		if len(call.Args) <= keyIndex || !isJWTParse(pass, file, sel) {
+			return
+		}
+		keyfunc := funcBody(pass, call.Args[keyIndex])
+		if keyfunc == nil {
+			return
+		}
+		if !hasValidMethodsOption(call.Args[keyIndex+1:]) && !checksSigningMethod(keyfunc) {
+			report(pass, call, checkJWTUncheckedAlg, "JWT keyfunc does not check token.Method, allowing algorithm confusion%s", onRoutes(routesReaching(pass, routes, file, call)))
+		}
+		ast.Inspect(keyfunc, func(n ast.Node) bool {
+			if ret, ok := n.(*ast.ReturnStmt); ok && len(ret.Results) > 0 && isConstantKey(pass, ret.Results[0]) {
+				report(pass, ret.Results[0], checkJWTHardcodedKey, "JWT verification key is hard-coded")
+			}
+			return true
+		})
+	case "ParseUnverified":
+		if len(call.Args) > 0 && src.contains(call.Args[0]) {
+			report(pass, call, checkJWTUnverified, "JWT from the request parsed without signature verification%s", onRoutes(routesReaching(pass, routes, file, call)))
+		}
+	case "SignedString":
+		if len(call.Args) == 1 && isConstantKey(pass, call.Args[0]) {
+			report(pass, call.Args[0], checkJWTHardcodedKey, "JWT signing key is hard-coded")
+		}
+	}
+}
+
+// isJWTParse reports whether sel is jwt.Parse, jwt.ParseWithClaims or
+// the methods of the same name of a jwt.Parser.
+func isJWTParse(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr) bool {
+	if isAnyPkgSelector(pass, file, sel, jwtPaths, sel.Sel.Name) {
+		return true
+	}
+	t := typeOf(pass.TypesInfo, sel.X)
+	if t == nil {
+		return extractIdent(sel.X) != ""
+	}
+	for _, path := range jwtPaths {
+		if isNamed(t, path, "Parser") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValidMethodsOption reports whether jwt.WithValidMethods is among
+// the parser options opts, which makes the parser check the algorithm.
+func hasValidMethodsOption(opts []ast.Expr) bool {
+	for _, opt := range opts {
+		if call, ok := opt.(*ast.CallExpr); ok && calleeName(call) == "WithValidMethods" {
+			return true
+		}
+	}
+	return false
+}
+
+// checksSigningMethod reports whether keyfunc reads token.Method, as
+// in `if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok`.
+func checksSigningMethod(keyfunc *ast.BlockStmt) (found bool) {
+	ast.Inspect(keyfunc, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Method" || sel.Sel.Name == "Alg") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func checkSessionStore(pass *analysis.Pass, file *ast.File, call *ast.CallExpr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	var keys []ast.Expr
+	switch {
+	case isPkgSelector(pass.TypesInfo, file, sel, gorillaSessionsPath, "NewCookieStore"):
+		keys = call.Args
+	case isPkgSelector(pass.TypesInfo, file, sel, gorillaSessionsPath, "NewFilesystemStore"):
+		if len(call.Args) > 0 {
+			keys = call.Args[1:]
+		}
+	default:
+		return
+	}
+
+	if len(keys) == 0 {
+		report(pass, call, checkSessionKey, "session store created without authentication key")
+		return
+	}
+	for _, key := range keys {
+		if isConstantKey(pass, key) {
+			report(pass, key, checkSessionKey, "session store key is hard-coded")
+		}
+	}
+}
+
+// isConstantKey reports whether expr is a key known at compile time:
+// []byte("secret"), a string constant, or a package level variable
+// initialized with one.
+func isConstantKey(pass *analysis.Pass, expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.SelectorExpr:
+		// The key accepting the none algorithm is reported as such.
+		if obj := pass.TypesInfo.Uses[e.Sel]; obj != nil && obj.Pkg() != nil {
+			if obj.Name() == "UnsafeAllowNoneSignatureType" && slices.Contains(jwtPaths, obj.Pkg().Path()) {
+				return false
+			}
+		} else if e.Sel.Name == "UnsafeAllowNoneSignatureType" {
+			return false
+		}
+	case *ast.CallExpr:
+		if _, ok := e.Fun.(*ast.ArrayType); ok && len(e.Args) == 1 {
+			return isConstant(pass.TypesInfo, e.Args[0])
+		}
+	case *ast.Ident:
+		if v, ok := pass.TypesInfo.Uses[e].(*types.Var); ok && v.Parent() == v.Pkg().Scope() {
+			if value := varValue(pass, v); value != nil {
+				return isConstantKey(pass, value)
+			}
+		}
+	}
+	return isConstant(pass.TypesInfo, expr)
+}
+
+// varValue returns the initial value of the package level variable v.
+func varValue(pass *analysis.Pass, v *types.Var) ast.Expr {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, name := range vs.Names {
+					if pass.TypesInfo.Defs[name] == v && i < len(vs.Values) {
+						return vs.Values[i]
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// funcBody returns the body of the function literal or declared
+// function expr.
+func funcBody(pass *analysis.Pass, expr ast.Expr) *ast.BlockStmt {
+	switch e := expr.(type) {
+	case *ast.FuncLit:
+		return e.Body
+	case *ast.Ident:
+		if decl := funcDecl(pass, pass.TypesInfo.Uses[e]); decl != nil {
+			return decl.Body
+		}
+	}
+	return nil
+}
+
+func importsAnyPath(file *ast.File, paths []string) bool {
+	for _, path := range paths {
+		if importsPath(file, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,447 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/analysis"
+)
+
+// VulnAnalyzer matches the dependencies of the module enclosing the
+// analysed package, from its go.mod and go.sum, against a local OSV
+// database. When the database lists the affected symbols only their
+// calls are reported.
+//
+// Standard library vulnerabilities are left out as the toolchain
+// building the code is not known from its sources.
+var VulnAnalyzer = &analysis.Analyzer{
+	Name:             "vulns",
+	Doc:              "report calls to vulnerable dependencies listed in a local OSV database",
+	Run:              runVulnsFromFlag,
+	RunDespiteErrors: true,
+}
+
+var (
+	checkVulnerableCall = RegisterCheck(&Check{
+		ID:         "vulnerable-call",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-1395",
+	})
+	checkVulnerableDependency = RegisterCheck(&Check{
+		ID:         "vulnerable-dependency",
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceLow,
+		CWE:        "CWE-1395",
+	})
+)
+
+var (
+	vulnDBFlag   string
+	loadVulnOnce sync.Once
+	flagVulnDB   *VulnDB
+	flagVulnErr  error
+)
+
+func init() {
+	VulnAnalyzer.Flags.StringVar(&vulnDBFlag, "db", "", "Dir of an OSV vulnerability database")
+}
+
+func runVulnsFromFlag(pass *analysis.Pass) (interface{}, error) {
+	if vulnDBFlag == "" {
+		return nil, nil
+	}
+	loadVulnOnce.Do(func() {
+		flagVulnDB, _, flagVulnErr = LoadVulnDB(vulnDBFlag)
+	})
+	if flagVulnErr != nil {
+		return nil, flagVulnErr
+	}
+	return nil, flagVulnDB.audit(pass)
+}
+
+// NewVulnAnalyzer returns an analyzer matching dependencies against
+// db instead of the database of the -db flag.
+func NewVulnAnalyzer(db *VulnDB) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "vulns",
+		Doc:  VulnAnalyzer.Doc,
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return nil, db.audit(pass)
+		},
+		RunDespiteErrors: true,
+	}
+}
+
+// OSV is the subset of an Open Source Vulnerability entry used to
+// match Go modules, see https://ossf.github.io/osv-schema/.
+type OSV struct {
+	ID       string        `json:"id"`
+	Summary  string        `json:"summary"`
+	Aliases  []string      `json:"aliases"`
+	Affected []OSVAffected `json:"affected"`
+}
+
+type OSVAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced   string `json:"introduced"`
+			Fixed        string `json:"fixed"`
+			LastAffected string `json:"last_affected"`
+		} `json:"events"`
+	} `json:"ranges"`
+	EcosystemSpecific struct {
+		Imports []struct {
+			Path    string   `json:"path"`
+			Symbols []string `json:"symbols"`
+		} `json:"imports"`
+	} `json:"ecosystem_specific"`
+}
+
+// affects reports whether version of the module is in the ranges of a.
+// Events of a range are expected in ascending order.
+func (a *OSVAffected) affects(version string) bool {
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		var affected bool
+		for _, e := range r.Events {
+			switch {
+			case e.Introduced != "":
+				if e.Introduced == "0" || semver.Compare(version, "v"+e.Introduced) >= 0 {
+					affected = true
+				}
+			case e.Fixed != "":
+				if semver.Compare(version, "v"+e.Fixed) >= 0 {
+					affected = false
+				}
+			case e.LastAffected != "":
+				if semver.Compare(version, "v"+e.LastAffected) > 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return false
+}
+
+// fixed returns the first version fixing the vulnerability after
+// version, if any.
+func (a *OSVAffected) fixed(version string) string {
+	for _, r := range a.Ranges {
+		for _, e := range r.Events {
+			if e.Fixed != "" && semver.Compare(version, "v"+e.Fixed) < 0 {
+				return "v" + e.Fixed
+			}
+		}
+	}
+	return ""
+}
+
+// VulnDB is an OSV database indexed by Go module path.
+type VulnDB struct {
+	byModule map[string][]*OSV
+
+	mu      sync.Mutex
+	modules map[string]*moduleDeps
+}
+
+// LoadVulnDB loads the OSV entries of the JSON files found under dir,
+// such as a mirror of vuln.go.dev, and returns a hash of their content
+// for caching. Files that are not OSV entries, such as indexes, are
+// skipped.
+func LoadVulnDB(dir string) (*VulnDB, string, error) {
+	db := &VulnDB{byModule: make(map[string][]*OSV), modules: make(map[string]*moduleDeps)}
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var entry OSV
+		if json.Unmarshal(b, &entry) != nil || entry.ID == "" {
+			return nil
+		}
+		h.Write(b)
+		seen := make(map[string]bool)
+		for _, a := range entry.Affected {
+			if a.Package.Ecosystem == "Go" && !seen[a.Package.Name] {
+				seen[a.Package.Name] = true
+				db.byModule[a.Package.Name] = append(db.byModule[a.Package.Name], &entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return db, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// moduleDeps are the versions of the dependencies of a module, keyed
+// by module path. Replacements are applied.
+type moduleDeps struct {
+	deps map[string]moduleVersion
+}
+
+type moduleVersion struct {
+	Path, Version string
+}
+
+// dependency returns the module providing the package importPath.
+func (m *moduleDeps) dependency(importPath string) (string, moduleVersion, bool) {
+	var best string
+	for path := range m.deps {
+		if (importPath == path || strings.HasPrefix(importPath, path+"/")) && len(path) > len(best) {
+			best = path
+		}
+	}
+	if best == "" {
+		return "", moduleVersion{}, false
+	}
+	return best, m.deps[best], true
+}
+
+// moduleOf returns the dependencies of the module enclosing dir,
+// parsed once per go.mod.
+func (db *VulnDB) moduleOf(dir string) (*moduleDeps, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(gomod); err == nil {
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			if m, ok := db.modules[gomod]; ok {
+				return m, nil
+			}
+			m, err := parseModuleDeps(gomod)
+			if err != nil {
+				return nil, err
+			}
+			db.modules[gomod] = m
+			return m, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// parseModuleDeps reads the requirements of go.mod. Modules missing
+// from it, as with go.mod files older than Go 1.17 listing direct
+// dependencies only, are taken at their highest version in go.sum.
+func parseModuleDeps(gomod string) (*moduleDeps, error) {
+	b, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.ParseLax(gomod, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	m := &moduleDeps{deps: make(map[string]moduleVersion)}
+	for _, r := range f.Require {
+		m.deps[r.Mod.Path] = moduleVersion{r.Mod.Path, r.Mod.Version}
+	}
+
+	if sum, err := os.ReadFile(filepath.Join(filepath.Dir(gomod), "go.sum")); err == nil {
+		for _, line := range strings.Split(string(sum), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") || required(f, fields[0]) {
+				continue
+			}
+			path, version := fields[0], fields[1]
+			if cur, ok := m.deps[path]; !ok || semver.Compare(version, cur.Version) > 0 {
+				m.deps[path] = moduleVersion{path, version}
+			}
+		}
+	}
+
+	for _, r := range f.Replace {
+		if _, ok := m.deps[r.Old.Path]; !ok || (r.Old.Version != "" && r.Old.Version != m.deps[r.Old.Path].Version) {
+			continue
+		}
+		if r.New.Version == "" {
+			// Replaced by a local directory, its version is unknown.
+			delete(m.deps, r.Old.Path)
+			continue
+		}
+		m.deps[r.Old.Path] = moduleVersion{r.New.Path, r.New.Version}
+	}
+	return m, nil
+}
+
+func required(f *modfile.File, path string) bool {
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *VulnDB) audit(pass *analysis.Pass) error {
+	if db == nil || len(pass.Files) == 0 {
+		return nil
+	}
+	m, err := db.moduleOf(filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name()))
+	if err != nil || m == nil {
+		return err
+	}
+
+	for _, file := range pass.Files {
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			modPath, mod, ok := m.dependency(importPath)
+			if !ok {
+				continue
+			}
+			for _, entry := range db.byModule[mod.Path] {
+				for i := range entry.Affected {
+					a := &entry.Affected[i]
+					if a.Package.Name != mod.Path || !a.affects(mod.Version) {
+						continue
+					}
+					db.reportAffected(pass, file, spec, importPath, modPath, mod, entry, a)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (db *VulnDB) reportAffected(pass *analysis.Pass, file *ast.File, spec *ast.ImportSpec, importPath, modPath string, mod moduleVersion, entry *OSV, a *OSVAffected) {
+	describe := func() string {
+		d := fmt.Sprintf("%s in %s@%s", entry.ID, modPath, mod.Version)
+		if fixed := a.fixed(mod.Version); fixed != "" {
+			d += ", fixed in " + fixed
+		}
+		if entry.Summary != "" {
+			d += ": " + entry.Summary
+		}
+		return d
+	}
+
+	if len(a.EcosystemSpecific.Imports) == 0 {
+		report(pass, spec, checkVulnerableDependency, "%s (%s imported, affected symbols unknown)", describe(), importPath)
+		return
+	}
+	for _, imp := range a.EcosystemSpecific.Imports {
+		if imp.Path != importPath {
+			continue
+		}
+		if len(imp.Symbols) == 0 {
+			report(pass, spec, checkVulnerableDependency, "%s (whole %s package affected)", describe(), importPath)
+			continue
+		}
+		for _, c := range findSymbolCalls(pass, file, importPath, imp.Symbols) {
+			if c.exact {
+				report(pass, c.call, checkVulnerableCall, "%s (%s called)", describe(), c.symbol)
+			} else {
+				report(pass, c.call, checkVulnerableDependency, "%s (%s possibly called)", describe(), c.symbol)
+			}
+		}
+	}
+}
+
+type symbolCall struct {
+	call   *ast.CallExpr
+	symbol string
+	exact  bool
+}
+
+// findSymbolCalls returns the calls in file to symbols of the package
+// importPath, as Func or Type.Method. Without type information methods
+// are matched by name only, and not exact: these guesses are only
+// returned when no call is exact.
+func findSymbolCalls(pass *analysis.Pass, file *ast.File, importPath string, symbols []string) []symbolCall {
+	funcs := make(map[string]bool)
+	// methods are the receiver types of each method name.
+	methods := make(map[string][]string)
+	for _, s := range symbols {
+		if typ, method, ok := strings.Cut(s, "."); ok {
+			methods[method] = append(methods[method], typ)
+		} else {
+			funcs[s] = true
+		}
+	}
+
+	var calls []*ast.CallExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		if c, ok := n.(*ast.CallExpr); ok {
+			calls = append(calls, c)
+		}
+		return true
+	})
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Pos() < calls[j].Pos() })
+
+	var found, guesses []symbolCall
+	for _, c := range calls {
+		sel, ok := c.Fun.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		if funcs[sel.Sel.Name] && isPkgSelector(pass.TypesInfo, file, sel, importPath, sel.Sel.Name) {
+			found = append(found, symbolCall{c, sel.Sel.Name, true})
+			continue
+		}
+		recvTypes, ok := methods[sel.Sel.Name]
+		if !ok {
+			continue
+		}
+		if fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func); ok {
+			if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+				for _, typ := range recvTypes {
+					if isNamed(recv.Type(), importPath, typ) {
+						found = append(found, symbolCall{c, typ + "." + sel.Sel.Name, true})
+					}
+				}
+			}
+			continue
+		}
+		if typeOf(pass.TypesInfo, sel.X) == nil {
+			var symbols []string
+			for _, typ := range recvTypes {
+				symbols = append(symbols, typ+"."+sel.Sel.Name)
+			}
+			guesses = append(guesses, symbolCall{c, strings.Join(symbols, " or "), false})
+		}
+	}
+	if len(found) == 0 {
+		return guesses
+	}
+	return found
+}
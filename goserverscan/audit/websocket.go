@@ -0,0 +1,249 @@
+package audit
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// WebSocketAnalyzer finds the handlers upgrading connections to
+// WebSocket, marks their routes and reports missing origin checks and
+// unbounded message sizes.
+var WebSocketAnalyzer = &analysis.Analyzer{
+	Name:             "websocket",
+	Doc:              "report WebSocket endpoints accepting any origin or unbounded messages",
+	Run:              runWebSocket,
+	Requires:         []*analysis.Analyzer{RoutesAnalyzer},
+	RunDespiteErrors: true,
+}
+
+var (
+	checkWebSocketOrigin = RegisterCheck(&Check{
+		ID:         "websocket-origin",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-1385",
+	})
+	checkWebSocketSkipVerify = RegisterCheck(&Check{
+		ID:         "websocket-insecure-skip-verify",
+		Severity:   SeverityHigh,
+		Confidence: ConfidenceHigh,
+		CWE:        "CWE-1385",
+	})
+	checkWebSocketReadLimit = RegisterCheck(&Check{
+		ID:         "websocket-read-limit",
+		Severity:   SeverityLow,
+		Confidence: ConfidenceMedium,
+		CWE:        "CWE-770",
+	})
+)
+
+const gorillaWebSocketPath = "github.com/gorilla/websocket"
+
+// nhooyrWebSocketPaths are the import paths of nhooyr.io/websocket,
+// now maintained as github.com/coder/websocket.
+var nhooyrWebSocketPaths = []string{"nhooyr.io/websocket", "github.com/coder/websocket"}
+
+func runWebSocket(pass *analysis.Pass) (interface{}, error) {
+	routes := pass.ResultOf[RoutesAnalyzer].(*Routes)
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.CompositeLit:
+				checkUpgraderOptions(pass, file, node)
+			case *ast.AssignStmt:
+				// upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+				for i, lhs := range node.Lhs {
+					if sel, ok := lhs.(*ast.SelectorExpr); ok && sel.Sel.Name == "CheckOrigin" && i < len(node.Rhs) && importsPath(file, gorillaWebSocketPath) {
+						if alwaysReturnsTrue(pass, node.Rhs[i]) {
+							report(pass, node.Rhs[i], checkWebSocketOrigin, "CheckOrigin accepts any origin, allowing cross-site WebSocket hijacking")
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if conn, ok := upgradeCall(pass, file, node); ok {
+					handleUpgrade(pass, file, routes, node, conn)
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+func checkUpgraderOptions(pass *analysis.Pass, file *ast.File, lit *ast.CompositeLit) {
+	sel, ok := lit.Type.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	switch {
+	case isPkgSelector(pass.TypesInfo, file, sel, gorillaWebSocketPath, "Upgrader"):
+		if v := fieldValue(lit, "CheckOrigin"); v != nil && alwaysReturnsTrue(pass, v) {
+			report(pass, v, checkWebSocketOrigin, "CheckOrigin accepts any origin, allowing cross-site WebSocket hijacking")
+		}
+	case isAnyPkgSelector(pass, file, sel, nhooyrWebSocketPaths, "AcceptOptions"):
+		if v := fieldValue(lit, "InsecureSkipVerify"); v != nil && extractIdent(v) == "true" {
+			report(pass, v, checkWebSocketSkipVerify, "InsecureSkipVerify disables the origin check, allowing cross-site WebSocket hijacking")
+		}
+		if v, ok := fieldValue(lit, "OriginPatterns").(*ast.CompositeLit); ok {
+			for _, elt := range v.Elts {
+				if exprValue(elt) == "*" {
+					report(pass, elt, checkWebSocketOrigin, "OriginPatterns accepts any origin, allowing cross-site WebSocket hijacking")
+				}
+			}
+		}
+	}
+}
+
+// upgradeCall reports whether call upgrades an HTTP connection, as
+// upgrader.Upgrade(w, r, nil) or websocket.Accept(w, r, opts), and
+// returns the name of the variable holding the connection.
+func upgradeCall(pass *analysis.Pass, file *ast.File, call *ast.CallExpr) (conn string, ok bool) {
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+	if !isSel || len(call.Args) != 3 {
+		return "", false
+	}
+	switch {
+	case sel.Sel.Name == "Upgrade" && importsPath(file, gorillaWebSocketPath):
+		if t := typeOf(pass.TypesInfo, sel.X); t != nil && !isNamed(t, gorillaWebSocketPath, "Upgrader") {
+			return "", false
+		}
+	case isAnyPkgSelector(pass, file, sel, nhooyrWebSocketPaths, "Accept"):
+	default:
+		return "", false
+	}
+
+	path, _ := astutil.PathEnclosingInterval(file, call.Pos(), call.End())
+	for _, n := range path {
+		if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Rhs) == 1 && assign.Rhs[0] == call {
+			return extractIdent(assign.Lhs[0]), true
+		}
+	}
+	return "", true
+}
+
+func handleUpgrade(pass *analysis.Pass, file *ast.File, routes *Routes, call *ast.CallExpr, conn string) {
+	served := routesReaching(pass, routes, file, call)
+	for _, route := range served {
+		route.WebSocket = true
+	}
+
+	body := enclosingBody(file, call)
+	if conn == "" || conn == "_" || body == nil {
+		return
+	}
+	limit, found := readLimit(body, conn)
+	switch {
+	case !found && isGorillaUpgrade(file, call):
+		report(pass, call, checkWebSocketReadLimit, "WebSocket connection%s has no read limit, allowing memory exhaustion with large messages", onRoutes(served))
+	case found && isNegative(limit):
+		report(pass, limit, checkWebSocketReadLimit, "WebSocket connection%s read limit is disabled, allowing memory exhaustion with large messages", onRoutes(served))
+	}
+}
+
+// enclosingBody returns the body of the innermost function enclosing
+// node.
+func enclosingBody(file *ast.File, node ast.Node) *ast.BlockStmt {
+	path, _ := astutil.PathEnclosingInterval(file, node.Pos(), node.End())
+	for _, n := range path {
+		switch fn := n.(type) {
+		case *ast.FuncLit:
+			return fn.Body
+		case *ast.FuncDecl:
+			return fn.Body
+		}
+	}
+	return nil
+}
+
+func isGorillaUpgrade(file *ast.File, call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Upgrade" && importsPath(file, gorillaWebSocketPath)
+}
+
+// readLimit returns the argument of conn.SetReadLimit(n) in body.
+func readLimit(body *ast.BlockStmt, conn string) (limit ast.Expr, found bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "SetReadLimit" && extractIdent(sel.X) == conn && len(call.Args) == 1 {
+				limit, found = call.Args[0], true
+			}
+		}
+		return !found
+	})
+	return limit, found
+}
+
+func isNegative(expr ast.Expr) bool {
+	u, ok := expr.(*ast.UnaryExpr)
+	return ok && u.Op == token.SUB
+}
+
+// alwaysReturnsTrue reports whether expr is a function, literal or
+// declared in the package, whose return statements all return true.
+func alwaysReturnsTrue(pass *analysis.Pass, expr ast.Expr) bool {
+	var body *ast.BlockStmt
+	switch e := expr.(type) {
+	case *ast.FuncLit:
+		body = e.Body
+	case *ast.Ident:
+		if decl := funcDecl(pass, pass.TypesInfo.Uses[e]); decl != nil {
+			body = decl.Body
+		}
+	}
+	if body == nil {
+		return false
+	}
+
+	var returns int
+	allTrue := true
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			returns++
+			if len(node.Results) != 1 || extractIdent(node.Results[0]) != "true" {
+				allTrue = false
+			}
+		}
+		return true
+	})
+	return returns > 0 && allTrue
+}
+
+// fieldValue returns the value of the field name in a keyed composite
+// literal.
+func fieldValue(lit *ast.CompositeLit, name string) ast.Expr {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && extractIdent(kv.Key) == name {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+func isAnyPkgSelector(pass *analysis.Pass, file *ast.File, sel *ast.SelectorExpr, paths []string, name string) bool {
+	for _, path := range paths {
+		if isPkgSelector(pass.TypesInfo, file, sel, path, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// onRoutes describes the routes for diagnostic messages.
+func onRoutes(routes []*Route) string {
+	if len(routes) == 0 {
+		return ""
+	}
+	var paths []string
+	for _, r := range routes {
+		paths = append(paths, r.Path)
+	}
+	return " on " + strings.Join(paths, ", ")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+// Baseline lists accepted findings. A finding is in the baseline when
+// its rule, file and normalized code match, whatever its line so that
+// unrelated edits moving code around do not resurface it.
+type Baseline struct {
+	Findings []Fingerprint `json:"findings"`
+
+	index map[Fingerprint]bool
+}
+
+type Fingerprint struct {
+	Rule string `json:"rule"`
+	File string `json:"file"`
+	Hash string `json:"hash"`
+}
+
+func NewFingerprint(root string, f *audit.Finding) Fingerprint {
+	file := f.Filename
+	if rel, err := filepath.Rel(root, f.Filename); err == nil {
+		file = rel
+	}
+	sum := sha256.Sum256([]byte(normalizeCode(f.Code)))
+	return Fingerprint{
+		Rule: f.RuleID,
+		File: filepath.ToSlash(file),
+		Hash: hex.EncodeToString(sum[:]),
+	}
+}
+
+// resultRoot returns the root the paths of res are relative to in
+// fingerprints: the directory or archive it was scanned from, or else
+// root.
+func resultRoot(root string, res *Result) string {
+	if res.Root != "" {
+		return res.Root
+	}
+	return root
+}
+
+// NewBaseline accepts all the findings of results.
+func NewBaseline(root string, results []*Result) *Baseline {
+	b := &Baseline{}
+	for _, res := range results {
+		for _, f := range res.Findings {
+			b.Findings = append(b.Findings, NewFingerprint(resultRoot(root, res), f))
+		}
+	}
+	return b
+}
+
+func LoadBaseline(path string) (*Baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b *Baseline
+	if err := json.NewDecoder(f).Decode(&b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Baseline) Write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (b *Baseline) Contains(fp Fingerprint) bool {
+	if b.index == nil {
+		b.index = make(map[Fingerprint]bool)
+		for _, f := range b.Findings {
+			b.index[f] = true
+		}
+	}
+	return b.index[fp]
+}
+
+// Filter removes from results the findings present in the baseline.
+func (b *Baseline) Filter(root string, results []*Result) {
+	for _, res := range results {
+		var kept []*audit.Finding
+		for _, f := range res.Findings {
+			if !b.Contains(NewFingerprint(resultRoot(root, res), f)) {
+				kept = append(kept, f)
+			}
+		}
+		res.Findings = kept
+	}
+}
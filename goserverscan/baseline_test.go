@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func TestBaseline(t *testing.T) {
+	finding := func(line int, code string) *audit.Finding {
+		return &audit.Finding{
+			Snippet: audit.Snippet{Code: code, Filename: "app/hash.go", Line: line},
+			RuleID:  "weak-hash",
+		}
+	}
+
+	res := &Result{Dir: "app", Findings: []*audit.Finding{finding(10, "h := md5.New()\n\th.Write(b)")}}
+	baseline := NewBaseline(".", []*Result{res})
+
+	moved := &Result{Dir: "app", Findings: []*audit.Finding{
+		finding(42, "h := md5.New()\n    h.Write(b)"),
+		finding(43, "md5.Sum(b)"),
+	}}
+	baseline.Filter(".", []*Result{moved})
+	if got, want := len(moved.Findings), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := moved.Findings[0].Line, 43; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
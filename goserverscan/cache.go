@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// analyserVersion must be bumped whenever the analyser output changes
+// so that results cached by previous versions are not reused.
+const analyserVersion = "23"
+
+// cache stores on disk the result of analysing a directory, keyed by
+// the content of its go files and of the go.mod and go.sum of its
+// module, the keys of the scanned directories it imports, the analyser
+// version and a salt identifying the rules and vulnerability database
+// in use.
+type cache struct {
+	dir  string
+	salt string
+}
+
+func newCache(dir, salt string) (*cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &cache{dir: dir, salt: salt}, nil
+}
+
+// key returns the key of dir importing the directories of keys deps.
+func (c *cache) key(dir string, deps []string) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(analyserVersion))
+	h.Write([]byte(c.salt))
+	for _, info := range infos {
+		if !filterNonTestGOFiles(info) {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(content)
+		h.Write([]byte(info.Name()))
+		h.Write(sum[:])
+	}
+	for _, name := range moduleFiles(dir) {
+		if content, err := ioutil.ReadFile(name); err == nil {
+			sum := sha256.Sum256(content)
+			h.Write([]byte(name))
+			h.Write(sum[:])
+		}
+	}
+	for _, dep := range deps {
+		h.Write([]byte(dep))
+	}
+	h.Write([]byte(dir))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// moduleFiles returns the go.mod and go.sum of the module enclosing
+// dir, if any.
+func moduleFiles(dir string) []string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(gomod); err == nil {
+			return []string{gomod, filepath.Join(dir, "go.sum")}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func (c *cache) get(key string) (*Result, bool) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var res *Result
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, false
+	}
+	return res, true
+}
+
+func (c *cache) put(key string, res *Result) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(c.dir, key)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+// Report is the JSON output of a scan, as produced with -format json
+// and compared with goserverscan diff.
+type Report struct {
+	Root    string
+	Results []*Result
+}
+
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(r)
+}
+
+func LoadReport(path string) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r *Report
+	if err := json.NewDecoder(f).Decode(&r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// loadOrScan loads the JSON report at path, or scans path when it is
+// a directory such as a checked out revision or a source archive.
+func loadOrScan(s *scanner, path string) (*Report, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() && !isArchive(path) {
+		return LoadReport(path)
+	}
+	results, err := s.ScanRoots([]string{path})
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Root: path, Results: results}, nil
+}
+
+// Diff lists the changes to the attack surface between two scans.
+type Diff struct {
+	AddedRoutes   []*audit.Route
+	RemovedRoutes []*audit.Route
+	ChangedRoutes []RouteChange
+	NewCalls      []*audit.OutGoingCall
+	NewFindings   []*audit.Finding
+	FixedFindings []*audit.Finding
+}
+
+// RouteChange is a route whose middleware changed.
+type RouteChange struct {
+	Route         *audit.Route
+	Before, After []string
+	LostAuth      bool
+}
+
+type routeEntry struct {
+	router *audit.Router
+	route  *audit.Route
+	file   string
+}
+
+// key identifies a route across scans by the router it is registered
+// on, its file and variable, and by what it serves rather than by line.
+func (e routeEntry) key() string {
+	return strings.Join([]string{e.router.Kind, e.file, routerName(e.router), e.router.Mount, strings.Join(e.route.Methods, ","), e.route.Path}, " ")
+}
+
+// routerName returns the variable or field a router is assigned to, as
+// s.router in s.router = mux.NewRouter(), or else its code.
+func routerName(r *audit.Router) string {
+	code := normalizeCode(r.Code)
+	for _, sep := range []string{":=", "=", ":"} {
+		if i := strings.Index(code, sep); i > 0 {
+			return strings.TrimPrefix(strings.TrimSpace(code[:i]), "var ")
+		}
+	}
+	return code
+}
+
+func (e routeEntry) middleware() []string {
+	return append(append([]string{}, e.router.Middleware...), e.route.Middleware...)
+}
+
+func NewDiff(before, after *Report) *Diff {
+	d := &Diff{}
+
+	// Routes of a same key, as registered twice, are matched in order.
+	oldRoutes := make(map[string][]routeEntry)
+	for _, e := range reportRoutes(before) {
+		oldRoutes[e.key()] = append(oldRoutes[e.key()], e)
+	}
+	matched := make(map[*audit.Route]bool)
+	for _, e := range reportRoutes(after) {
+		k := e.key()
+		if len(oldRoutes[k]) == 0 {
+			d.AddedRoutes = append(d.AddedRoutes, e.route)
+			continue
+		}
+		old := oldRoutes[k][0]
+		oldRoutes[k] = oldRoutes[k][1:]
+		matched[old.route] = true
+		if b, a := old.middleware(), e.middleware(); strings.Join(b, ",") != strings.Join(a, ",") || old.route.Authenticated != e.route.Authenticated {
+			d.ChangedRoutes = append(d.ChangedRoutes, RouteChange{
+				Route:    e.route,
+				Before:   b,
+				After:    a,
+				LostAuth: old.route.Authenticated && !e.route.Authenticated,
+			})
+		}
+	}
+	for _, e := range reportRoutes(before) {
+		if !matched[e.route] {
+			d.RemovedRoutes = append(d.RemovedRoutes, e.route)
+		}
+	}
+
+	// Calls are counted by code so that a second identical call is new.
+	oldCalls := make(map[string]int)
+	for _, res := range before.Results {
+		for _, c := range res.OutGoingCalls {
+			oldCalls[c.Kind+" "+normalizeCode(c.Code)]++
+		}
+	}
+	for _, res := range after.Results {
+		for _, c := range res.OutGoingCalls {
+			k := c.Kind + " " + normalizeCode(c.Code)
+			if oldCalls[k] > 0 {
+				oldCalls[k]--
+				continue
+			}
+			d.NewCalls = append(d.NewCalls, c)
+		}
+	}
+
+	oldFindings := make(map[Fingerprint]int)
+	for _, res := range before.Results {
+		for _, f := range res.Findings {
+			oldFindings[NewFingerprint(resultRoot(before.Root, res), f)]++
+		}
+	}
+	newFindings := make(map[Fingerprint]int)
+	for _, res := range after.Results {
+		for _, f := range res.Findings {
+			fp := NewFingerprint(resultRoot(after.Root, res), f)
+			newFindings[fp]++
+			if oldFindings[fp] >= newFindings[fp] {
+				continue
+			}
+			d.NewFindings = append(d.NewFindings, f)
+		}
+	}
+	kept := make(map[Fingerprint]int)
+	for _, res := range before.Results {
+		for _, f := range res.Findings {
+			fp := NewFingerprint(resultRoot(before.Root, res), f)
+			kept[fp]++
+			if newFindings[fp] >= kept[fp] {
+				continue
+			}
+			d.FixedFindings = append(d.FixedFindings, f)
+		}
+	}
+	return d
+}
+
+func reportRoutes(r *Report) (entries []routeEntry) {
+	for _, res := range r.Results {
+		root := resultRoot(r.Root, res)
+		for _, router := range res.Routers {
+			file := router.Filename
+			if rel, err := filepath.Rel(root, file); err == nil {
+				file = rel
+			}
+			for _, route := range router.Routes {
+				entries = append(entries, routeEntry{router, route, filepath.ToSlash(file)})
+			}
+		}
+	}
+	return entries
+}
+
+func normalizeCode(code string) string {
+	return strings.Join(strings.Fields(code), " ")
+}
+
+// PrintDiff writes d as lines prefixed with + for additions, - for
+// removals and ~ for changes.
+func PrintDiff(d *Diff, w io.Writer) {
+	for _, r := range d.AddedRoutes {
+		fmt.Fprintf(w, "+ Route %s%s\n", r.Snippet, routeDetails(r))
+	}
+	for _, r := range d.RemovedRoutes {
+		fmt.Fprintf(w, "- Route %s%s\n", r.Snippet, routeDetails(r))
+	}
+	for _, c := range d.ChangedRoutes {
+		lost := ""
+		if c.LostAuth {
+			lost = " LOST AUTHENTICATION"
+		}
+		fmt.Fprintf(w, "~ Route %s middleware: [%s] -> [%s]%s\n", c.Route.Snippet, strings.Join(c.Before, ","), strings.Join(c.After, ","), lost)
+	}
+	for _, c := range d.NewCalls {
+		fmt.Fprintf(w, "+ OutGoingCall %s\n", c)
+	}
+	for _, f := range d.NewFindings {
+		fmt.Fprintf(w, "+ Finding %s\n", f)
+	}
+	for _, f := range d.FixedFindings {
+		fmt.Fprintf(w, "- Finding %s\n", f)
+	}
+	fmt.Fprintf(w, "\n%d added, %d removed, %d changed routes, %d new outgoing calls, %d new and %d fixed findings\n",
+		len(d.AddedRoutes), len(d.RemovedRoutes), len(d.ChangedRoutes), len(d.NewCalls), len(d.NewFindings), len(d.FixedFindings))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func TestDiff(t *testing.T) {
+	route := func(path string, line int, auth bool, middleware ...string) *audit.Route {
+		return &audit.Route{
+			Snippet:       audit.Snippet{Code: "m.Handle(" + path + ")", Filename: "main.go", Line: line},
+			Path:          path,
+			Middleware:    middleware,
+			Authenticated: auth,
+		}
+	}
+	report := func(root string, routes []*audit.Route, calls []string, findings []string) *Report {
+		res := &Result{Routers: []*audit.Router{{Kind: "net/http", Routes: routes}}}
+		for _, c := range calls {
+			res.OutGoingCalls = append(res.OutGoingCalls, &audit.OutGoingCall{Snippet: audit.Snippet{Code: c}, Kind: "http.Get"})
+		}
+		for _, code := range findings {
+			res.Findings = append(res.Findings, &audit.Finding{Snippet: audit.Snippet{Code: code, Filename: root + "/main.go"}, RuleID: "weak-hash"})
+		}
+		return &Report{Root: root, Results: []*Result{res}}
+	}
+
+	before := report("old",
+		[]*audit.Route{route("/", 10, false), route("/admin", 11, true, "auth"), route("/legacy", 12, false)},
+		[]string{`http.Get("https://a")`},
+		[]string{"md5.New()", "sha1.New()"},
+	)
+	after := report("new",
+		[]*audit.Route{route("/", 20, false), route("/admin", 21, false, "logging"), route("/export", 22, false)},
+		[]string{`http.Get("https://a")`, `http.Get(u)`},
+		[]string{"md5.New()", "des.NewCipher(k)"},
+	)
+
+	d := NewDiff(before, after)
+	var buf bytes.Buffer
+	PrintDiff(d, &buf)
+	got := buf.String()
+
+	for _, want := range []string{
+		"+ Route main.go:22 'm.Handle(/export)'\n",
+		"- Route main.go:12 'm.Handle(/legacy)'\n",
+		"~ Route main.go:21 'm.Handle(/admin)' middleware: [auth] -> [logging] LOST AUTHENTICATION\n",
+		"+ OutGoingCall http call at line :0 (http.Get)\n",
+		"+ Finding [/] weak-hash:  at new/main.go:0 'des.NewCipher(k)'\n",
+		"- Finding [/] weak-hash:  at old/main.go:0 'sha1.New()'\n",
+		"1 added, 1 removed, 1 changed routes, 1 new outgoing calls, 1 new and 1 fixed findings\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestDiffSamePath(t *testing.T) {
+	router := func(file, code string, auth bool) *audit.Router {
+		return &audit.Router{
+			Snippet: audit.Snippet{Code: code, Filename: file},
+			Kind:    "net/http",
+			Routes: []*audit.Route{{
+				Snippet:       audit.Snippet{Code: "m.Handle(/admin)", Filename: file},
+				Path:          "/admin",
+				Authenticated: auth,
+			}},
+		}
+	}
+	before := &Report{Results: []*Result{{Routers: []*audit.Router{
+		router("api.go", "api := http.NewServeMux()", true),
+		router("public.go", "public := http.NewServeMux()", false),
+	}}}}
+	after := &Report{Results: []*Result{{Routers: []*audit.Router{
+		router("api.go", "api := http.NewServeMux()", false),
+		router("public.go", "public := http.NewServeMux()", false),
+	}}}}
+
+	d := NewDiff(before, after)
+	if len(d.ChangedRoutes) != 1 || d.ChangedRoutes[0].Route.Filename != "api.go" || !d.ChangedRoutes[0].LostAuth {
+		t.Fatalf("got %+v, want api.go /admin losing authentication", d.ChangedRoutes)
+	}
+	if len(d.AddedRoutes) != 0 || len(d.RemovedRoutes) != 0 {
+		t.Errorf("got %d added and %d removed routes, want none", len(d.AddedRoutes), len(d.RemovedRoutes))
+	}
+}
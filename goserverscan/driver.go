@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// driver runs analyzers on the packages of a single directory.
+//
+// Packages are type checked leniently: dependencies that cannot be
+// imported, as when scanned code comes without its modules, leave
+// holes in the type information that analyzers work around. Packages
+// of other scanned directories are imported as already checked, so
+// that facts flow from them through a factStore shared by the scan.
+type driver struct {
+	analyzers []*analysis.Analyzer
+	importer  *lockedImporter
+}
+
+func newDriver(analyzers []*analysis.Analyzer) *driver {
+	return &driver{
+		analyzers: analyzers,
+		importer:  &lockedImporter{imp: importer.Default()},
+	}
+}
+
+// lockedImporter serializes imports as importers are not safe for
+// concurrent use.
+type lockedImporter struct {
+	mu  sync.Mutex
+	imp types.Importer
+}
+
+func (i *lockedImporter) Import(path string) (*types.Package, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.imp.Import(path)
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+type factKey struct {
+	obj types.Object
+	pkg *types.Package
+	typ reflect.Type
+}
+
+// factStore holds the facts exported by the packages of a scan, for
+// the packages importing them.
+type factStore struct {
+	mu    sync.Mutex
+	facts map[factKey]analysis.Fact
+}
+
+func newFactStore() *factStore {
+	return &factStore{facts: make(map[factKey]analysis.Fact)}
+}
+
+func (s *factStore) get(k factKey) (analysis.Fact, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.facts[k]
+	return f, ok
+}
+
+func (s *factStore) set(k factKey, f analysis.Fact) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.facts[k] = f
+}
+
+func (s *factStore) all() map[factKey]analysis.Fact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make(map[factKey]analysis.Fact, len(s.facts))
+	for k, f := range s.facts {
+		all[k] = f
+	}
+	return all
+}
+
+// pkgRun holds the state of the analysis of one package.
+type pkgRun struct {
+	pass     analysis.Pass
+	files    []*ast.File
+	results  map[*analysis.Analyzer]interface{}
+	facts    *factStore
+	findings []*audit.Finding
+}
+
+// Run analyses files, all of a same package, and adds what is found
+// to res. Imports of deps, scanned packages by import path, resolve
+// to them. It returns the type checked package.
+func (d *driver) Run(fset *token.FileSet, path string, files []*ast.File, deps map[string]*types.Package, facts *factStore, res *Result) (*types.Package, error) {
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if pkg, ok := deps[path]; ok {
+				return pkg, nil
+			}
+			return d.importer.Import(path)
+		}),
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(path, fset, files, info)
+
+	run := &pkgRun{
+		files:   files,
+		results: make(map[*analysis.Analyzer]interface{}),
+		facts:   facts,
+	}
+	run.pass = analysis.Pass{
+		Fset:       fset,
+		Files:      files,
+		Pkg:        pkg,
+		TypesInfo:  info,
+		TypesSizes: types.SizesFor("gc", "amd64"),
+	}
+
+	for _, a := range d.analyzers {
+		if err := run.analyse(a, make(map[*analysis.Analyzer]bool)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range run.results {
+		switch v := r.(type) {
+		case *audit.Routes:
+			res.Routers = append(res.Routers, v.Routers...)
+		case []*audit.OutGoingCall:
+			res.OutGoingCalls = append(res.OutGoingCalls, v...)
+		case []*audit.Handler:
+			res.Handlers = append(res.Handlers, v...)
+		case []*audit.ConfigKey:
+			res.Config = append(res.Config, v...)
+		case []*audit.StaticMount:
+			res.Static = append(res.Static, v...)
+		}
+	}
+	res.Findings = append(res.Findings, run.findings...)
+
+	sort.SliceStable(res.Findings, func(i, j int) bool {
+		return res.Findings[i].Filename < res.Findings[j].Filename ||
+			(res.Findings[i].Filename == res.Findings[j].Filename && res.Findings[i].Line < res.Findings[j].Line)
+	})
+	return pkg, nil
+}
+
+func (run *pkgRun) analyse(a *analysis.Analyzer, visiting map[*analysis.Analyzer]bool) error {
+	if _, done := run.results[a]; done {
+		return nil
+	}
+	if visiting[a] {
+		return fmt.Errorf("cycle in analyzers requirements at %s", a.Name)
+	}
+	visiting[a] = true
+
+	resultOf := make(map[*analysis.Analyzer]interface{})
+	for _, req := range a.Requires {
+		if err := run.analyse(req, visiting); err != nil {
+			return err
+		}
+		resultOf[req] = run.results[req]
+	}
+
+	pass := run.pass
+	pass.Analyzer = a
+	pass.ResultOf = resultOf
+	pass.Report = func(d analysis.Diagnostic) {
+		run.findings = append(run.findings, run.newFinding(d))
+	}
+	pass.ImportObjectFact = func(obj types.Object, fact analysis.Fact) bool {
+		return run.importFact(factKey{obj: obj, typ: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ExportObjectFact = func(obj types.Object, fact analysis.Fact) {
+		run.facts.set(factKey{obj: obj, typ: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ImportPackageFact = func(pkg *types.Package, fact analysis.Fact) bool {
+		return run.importFact(factKey{pkg: pkg, typ: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ExportPackageFact = func(fact analysis.Fact) {
+		run.facts.set(factKey{pkg: pass.Pkg, typ: reflect.TypeOf(fact)}, fact)
+	}
+	pass.AllObjectFacts = func() (facts []analysis.ObjectFact) {
+		for k, f := range run.facts.all() {
+			if k.obj != nil {
+				facts = append(facts, analysis.ObjectFact{Object: k.obj, Fact: f})
+			}
+		}
+		return facts
+	}
+	pass.AllPackageFacts = func() (facts []analysis.PackageFact) {
+		for k, f := range run.facts.all() {
+			if k.pkg != nil {
+				facts = append(facts, analysis.PackageFact{Package: k.pkg, Fact: f})
+			}
+		}
+		return facts
+	}
+
+	result, err := a.Run(&pass)
+	if err != nil {
+		return fmt.Errorf("%s: %s", a.Name, err)
+	}
+	run.results[a] = result
+	return nil
+}
+
+func (run *pkgRun) importFact(k factKey, fact analysis.Fact) bool {
+	f, ok := run.facts.get(k)
+	if !ok {
+		return false
+	}
+	reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(f).Elem())
+	return true
+}
+
+func (run *pkgRun) newFinding(d analysis.Diagnostic) *audit.Finding {
+	f := &audit.Finding{
+		RuleID:  d.Category,
+		Message: d.Message,
+	}
+	if c, ok := audit.LookupCheck(d.Category); ok {
+		f.Severity, f.Confidence, f.CWE = c.Severity, c.Confidence, c.CWE
+	}
+
+	end := d.End
+	if !end.IsValid() {
+		end = d.Pos
+	}
+	for _, file := range run.files {
+		if file.FileStart <= d.Pos && d.Pos <= file.FileEnd {
+			path, _ := astutil.PathEnclosingInterval(file, d.Pos, end)
+			if len(path) > 0 {
+				f.Snippet = audit.NewSnippet(run.pass.Fset, path[0])
+				return f
+			}
+		}
+	}
+	pos := run.pass.Fset.Position(d.Pos)
+	f.Filename, f.Line = pos.Filename, pos.Line
+	return f
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	_ "embed"
+	goscanner "go/scanner"
+	"go/token"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+//go:embed report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"highlight": highlightGo,
+	"join":      func(s []string) string { return strings.Join(s, ", ") },
+}).Parse(reportHTML))
+
+type htmlRoute struct {
+	Router *audit.Router
+	*audit.Route
+}
+
+type findingGroup struct {
+	Severity audit.Severity
+	Findings []*audit.Finding
+}
+
+type htmlReport struct {
+	Modules  []string
+	Routes   []htmlRoute
+	Handlers []*audit.Handler
+	Calls    []*audit.OutGoingCall
+	Config   []*audit.ConfigKey
+	Static   []*audit.StaticMount
+	Findings []findingGroup
+	Total    int
+}
+
+// PrintHTML writes results as a single self-contained HTML page.
+func PrintHTML(results []*Result, w io.Writer) error {
+	var report htmlReport
+	bySeverity := make(map[audit.Severity][]*audit.Finding)
+	for _, res := range results {
+		if m := moduleName(res); m != "" && !slices.Contains(report.Modules, m) {
+			report.Modules = append(report.Modules, m)
+		}
+		for _, router := range res.Routers {
+			for _, route := range router.Routes {
+				report.Routes = append(report.Routes, htmlRoute{router, route})
+			}
+		}
+		report.Handlers = append(report.Handlers, res.Handlers...)
+		report.Calls = append(report.Calls, res.OutGoingCalls...)
+		report.Config = append(report.Config, res.Config...)
+		report.Static = append(report.Static, res.Static...)
+		for _, f := range res.Findings {
+			bySeverity[f.Severity] = append(bySeverity[f.Severity], f)
+			report.Total++
+		}
+	}
+	for i := len(audit.Severities) - 1; i >= 0; i-- {
+		sev := audit.Severities[i]
+		if len(bySeverity[sev]) > 0 {
+			report.Findings = append(report.Findings, findingGroup{sev, bySeverity[sev]})
+		}
+		delete(bySeverity, sev)
+	}
+	// Findings of unknown severity, as from older scans, are listed last.
+	var other []*audit.Finding
+	for _, res := range results {
+		for _, f := range res.Findings {
+			if len(bySeverity[f.Severity]) > 0 {
+				other = append(other, f)
+			}
+		}
+	}
+	if len(other) > 0 {
+		report.Findings = append(report.Findings, findingGroup{"other", other})
+	}
+	return reportTemplate.Execute(w, report)
+}
+
+// highlightGo marks up Go code with spans classed by token kind.
+func highlightGo(code string) template.HTML {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(code))
+	var s goscanner.Scanner
+	s.Init(file, []byte(code), func(token.Position, string) {}, goscanner.ScanComments)
+
+	var b strings.Builder
+	var last int
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		off := file.Offset(pos)
+		if off < last || off+len(text) > len(code) {
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(code[last:off]))
+
+		var class string
+		switch {
+		case tok.IsKeyword():
+			class = "kw"
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		case tok == token.COMMENT:
+			class = "com"
+		}
+		if class != "" {
+			b.WriteString(`<span class="` + class + `">` + template.HTMLEscapeString(text) + `</span>`)
+		} else {
+			b.WriteString(template.HTMLEscapeString(text))
+		}
+		last = off + len(text)
+	}
+	b.WriteString(template.HTMLEscapeString(code[last:]))
+	return template.HTML(b.String())
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func TestPrintHTML(t *testing.T) {
+	results := []*Result{{
+		Routers: []*audit.Router{{
+			Kind: "net/http",
+			Routes: []*audit.Route{{
+				Snippet: audit.Snippet{Code: `m.HandleFunc("/admin", admin)`, Filename: "main.go", Line: 12},
+				Path:    "/admin",
+				Handler: "admin",
+			}},
+		}},
+		Findings: []*audit.Finding{{
+			Snippet:  audit.Snippet{Code: `db.Query("SELECT " + r.FormValue("q"))`, Filename: "main.go", Line: 20},
+			RuleID:   "sql-from-request",
+			Severity: audit.SeverityHigh,
+			Message:  "query built from <request>",
+		}, {
+			Snippet:  audit.Snippet{Code: `md5.New()`, Filename: "main.go", Line: 30},
+			RuleID:   "weak-hash",
+			Severity: "severe",
+			Message:  "MD5 is not collision resistant",
+		}},
+	}}
+
+	var buf bytes.Buffer
+	if err := PrintHTML(results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<code>/admin</code>",
+		`<span class="tag high">high</span> 1`,
+		`db.Query(<span class="str">&#34;SELECT &#34;</span> + r.FormValue(<span class="str">&#34;q&#34;</span>))`,
+		"query built from &lt;request&gt;",
+		`<span class="tag other">other</span> 1`,
+		"MD5 is not collision resistant",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in report", want)
+		}
+	}
+}
+
+func TestHighlightGo(t *testing.T) {
+	got := string(highlightGo("if err != nil { return 42 } // done"))
+	want := `<span class="kw">if</span> err != nil { <span class="kw">return</span> <span class="num">42</span> } <span class="com">// done</span>`
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// importGraph is the import graph of the packages of scanned
+// directories, so that packages are analysed after the packages they
+// import. Imports are resolved to directories by the path of their
+// module or, outside of modules, by the last elements of the directory
+// path as in GOPATH layouts.
+type importGraph struct {
+	dirs []string
+	// paths are the import paths of dirs, or their slash path outside
+	// of modules.
+	paths []string
+	// imports maps the import paths of other scanned directories
+	// imported by each directory to their index.
+	imports []map[string]int
+}
+
+func newImportGraph(dirs []string) (*importGraph, error) {
+	g := &importGraph{
+		dirs:    dirs,
+		paths:   make([]string, len(dirs)),
+		imports: make([]map[string]int, len(dirs)),
+	}
+	inModule := make([]bool, len(dirs))
+	byPath := make(map[string]int)
+	modules := make(map[string]string)
+	for i, dir := range dirs {
+		g.paths[i], inModule[i] = importPath(dir, modules)
+		byPath[g.paths[i]] = i
+	}
+
+	for i, dir := range dirs {
+		g.imports[i] = make(map[string]int)
+		packages, err := parser.ParseDir(token.NewFileSet(), dir, filterNonTestGOFiles, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range packages {
+			for _, file := range pkg.Files {
+				for _, spec := range file.Imports {
+					path, err := strconv.Unquote(spec.Path.Value)
+					if err != nil {
+						continue
+					}
+					j, ok := byPath[path]
+					if !ok && !inModule[i] {
+						j, ok = g.gopathDir(path, inModule)
+					}
+					if ok && j != i {
+						g.imports[i][path] = j
+					}
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// gopathDir returns the only directory outside of modules whose path
+// ends with the import path.
+func (g *importGraph) gopathDir(path string, inModule []bool) (int, bool) {
+	found := -1
+	for j, p := range g.paths {
+		if !inModule[j] && strings.HasSuffix(p, "/"+path) {
+			if found >= 0 {
+				return -1, false
+			}
+			found = j
+		}
+	}
+	return found, found >= 0
+}
+
+// order returns the indexes of dirs with each directory after the
+// ones it imports, dropping imports that close a cycle.
+func (g *importGraph) order() []int {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make([]int, len(g.dirs))
+	order := make([]int, 0, len(g.dirs))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		for _, path := range sortedImports(g.imports[i]) {
+			j := g.imports[i][path]
+			switch state[j] {
+			case visiting:
+				delete(g.imports[i], path)
+			case 0:
+				visit(j)
+			}
+		}
+		state[i] = visited
+		order = append(order, i)
+	}
+	for i := range g.dirs {
+		if state[i] == 0 {
+			visit(i)
+		}
+	}
+	return order
+}
+
+func sortedImports(imports map[string]int) (paths []string) {
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// importPath returns the import path of dir from the go.mod of its
+// module, and whether it is in a module. modules caches the module
+// paths of module directories.
+func importPath(dir string, modules map[string]string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(dir), false
+	}
+	files := moduleFiles(abs)
+	if len(files) == 0 {
+		return filepath.ToSlash(dir), false
+	}
+	modDir := filepath.Dir(files[0])
+	modPath, ok := modules[modDir]
+	if !ok {
+		if content, err := ioutil.ReadFile(files[0]); err == nil {
+			modPath = modfile.ModulePath(content)
+		}
+		modules[modDir] = modPath
+	}
+	if modPath == "" {
+		return filepath.ToSlash(dir), false
+	}
+	rel, err := filepath.Rel(modDir, abs)
+	if err != nil || rel == "." {
+		return modPath, true
+	}
+	return modPath + "/" + filepath.ToSlash(rel), true
+}
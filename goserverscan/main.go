@@ -2,47 +2,221 @@ package main
 
 import (
 	"flag"
-	"go/parser"
-	"go/token"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+// Exit codes so that CI pipelines can tell a failed gate from a
+// broken scan.
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
 )
 
 var (
-	dirFlag string
+	dirFlag     string
+	workersFlag int
+	cacheFlag   string
+	rulesFlag   string
+	vulnDBFlag  string
+	csrfFlag    string
+	rateFlag    string
+
+	baselineFlag       string
+	updateBaselineFlag bool
+	failOnFlag         string
+	formatFlag         string
+	baseURLFlag        string
 )
 
 func main() {
-	flag.StringVar(&dirFlag, "dir", "./", "Dir where to parse go files")
-	flag.Parse()
+	flag.StringVar(&dirFlag, "dir", "./", "Comma separated dirs or .zip/.tar.gz source archives where to parse go files")
+	flag.IntVar(&workersFlag, "workers", runtime.NumCPU(), "Number of directories parsed and analysed concurrently")
+	flag.StringVar(&cacheFlag, "cache", "", "Dir where to cache analysis results, not cached by default (e.g. ~/.cache/goserverscan)")
+	flag.StringVar(&rulesFlag, "rules", "", "Dir of YAML pattern rules to apply")
+	flag.StringVar(&vulnDBFlag, "vulndb", "", "Dir of an OSV vulnerability database to match dependencies against")
+	flag.StringVar(&csrfFlag, "csrf-middleware", "", "Comma separated names of functions protecting handlers against CSRF")
+	flag.StringVar(&rateFlag, "rate-limit-middleware", "", "Comma separated names of functions rate limiting handlers")
+	flag.StringVar(&baselineFlag, "baseline", "", "JSON file of accepted findings to hide")
+	flag.BoolVar(&updateBaselineFlag, "update-baseline", false, "Write all current findings to the baseline file, ignoring -fail-on")
+	flag.StringVar(&failOnFlag, "fail-on", "", "Exit with non zero status on findings of this severity or above (info, low, medium, high, critical)")
+	flag.StringVar(&formatFlag, "format", "text", "Output format (text, html, json, targets)")
+	flag.StringVar(&baseURLFlag, "base-url", "http://localhost:8080", "Base URL of the request templates written with -format targets")
+
+	// goserverscan diff OLD NEW compares two scans, and goserverscan
+	// query SCAN [QUERY] explores one.
+	args := os.Args[1:]
+	diffMode := len(args) > 0 && args[0] == "diff"
+	queryMode := len(args) > 0 && args[0] == "query"
+	if diffMode || queryMode {
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+	log.SetFlags(0)
+
+	switch formatFlag {
+	case "text", "html", "json", "targets":
+	default:
+		fatal(fmt.Sprintf("unknown format %q", formatFlag))
+	}
+
+	var failOn audit.Severity
+	if failOnFlag != "" {
+		sev, err := audit.ParseSeverity(failOnFlag)
+		if err != nil {
+			fatal(err)
+		}
+		failOn = sev
+	}
+
+	if updateBaselineFlag && baselineFlag == "" {
+		fatal("missing baseline param when updating baseline")
+	}
+
+	s := &scanner{workers: workersFlag}
+
+	var (
+		rules     []*audit.Rule
+		rulesHash string
+	)
+	if rulesFlag != "" {
+		var err error
+		if rules, rulesHash, err = audit.LoadRules(rulesFlag); err != nil {
+			fatal(err)
+		}
+	}
+
+	var (
+		vulnDB     *audit.VulnDB
+		vulnDBHash string
+	)
+	if vulnDBFlag != "" {
+		var err error
+		if vulnDB, vulnDBHash, err = audit.LoadVulnDB(vulnDBFlag); err != nil {
+			fatal(err)
+		}
+	}
+	if err := audit.CSRFAnalyzer.Flags.Set("middleware", csrfFlag); err != nil {
+		fatal(err)
+	}
+	if err := audit.RateLimitAnalyzer.Flags.Set("middleware", rateFlag); err != nil {
+		fatal(err)
+	}
+	s.driver = newDriver(audit.Analyzers(rules, vulnDB))
+
+	if cacheFlag != "" {
+		c, err := newCache(cacheFlag, rulesHash+vulnDBHash+csrfFlag+"|"+rateFlag)
+		if err != nil {
+			fatal(err)
+		}
+		s.cache = c
+	}
+
+	if diffMode {
+		if flag.NArg() != 2 {
+			fatal("usage: goserverscan diff [flags] OLD NEW (JSON scans or dirs)")
+		}
+		before, err := loadOrScan(s, flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		after, err := loadOrScan(s, flag.Arg(1))
+		if err != nil {
+			fatal(err)
+		}
+		PrintDiff(NewDiff(before, after), os.Stdout)
+		os.Exit(exitOK)
+	}
 
-	dirs := []string{}
-	if err := filepath.Walk(dirFlag, func(path string, f os.FileInfo, err error) error {
-		if f.IsDir() {
-			dirs = append(dirs, path)
+	if queryMode {
+		if flag.NArg() != 1 && flag.NArg() != 2 {
+			fatal("usage: goserverscan query [flags] SCAN [QUERY] (JSON scan or dir, queries read from stdin without QUERY)")
+		}
+		report, err := loadOrScan(s, flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		if flag.NArg() == 1 {
+			if err := Explore(report, os.Stdin, os.Stdout, os.Stderr); err != nil {
+				fatal(err)
+			}
+			os.Exit(exitOK)
 		}
-		return nil
-	}); err != nil {
-		log.Fatal(err)
+		q, err := ParseQuery(flag.Arg(1))
+		if err != nil {
+			fatal(err)
+		}
+		if err := PrintQuery(q.Run(report), formatFlag == "json", os.Stdout); err != nil {
+			fatal(err)
+		}
+		os.Exit(exitOK)
+	}
+
+	results, err := s.ScanRoots(strings.Split(dirFlag, ","))
+	if err != nil {
+		fatal(err)
 	}
 
-	for _, dir := range dirs {
-		fset := token.NewFileSet()
-		packages, err := parser.ParseDir(fset, dir, filterNonTestGOFiles, parser.AllErrors)
+	if updateBaselineFlag {
+		if err := NewBaseline(dirFlag, results).Write(baselineFlag); err != nil {
+			fatal(err)
+		}
+	} else if baselineFlag != "" {
+		baseline, err := LoadBaseline(baselineFlag)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
+		}
+		baseline.Filter(dirFlag, results)
+	}
+
+	switch formatFlag {
+	case "json":
+		if err := (&Report{Root: dirFlag, Results: results}).Write(os.Stdout); err != nil {
+			fatal(err)
+		}
+	case "targets":
+		if err := WriteTargets(NewTargets(baseURLFlag, results), os.Stdout); err != nil {
+			fatal(err)
+		}
+	case "html":
+		if err := PrintHTML(results, os.Stdout); err != nil {
+			fatal(err)
 		}
+	default:
+		PrintResults(results, os.Stdout)
+		fmt.Fprintln(os.Stdout)
+		PrintSummary(results, os.Stdout)
+	}
+
+	// Refreshing the baseline accepts the findings, it does not fail.
+	if failOn != "" && !updateBaselineFlag && hasFindingsAbove(results, failOn) {
+		log.Printf("findings at or above %s severity", failOn)
+		os.Exit(exitFindings)
+	}
+	os.Exit(exitOK)
+}
 
-		analyser := NewAnalyser(fset)
-		for _, pkg := range packages {
-			for _, f := range pkg.Files {
-				analyser.Run(f)
+func hasFindingsAbove(results []*Result, threshold audit.Severity) bool {
+	for _, res := range results {
+		for _, f := range res.Findings {
+			if f.Severity.AtLeast(threshold) {
+				return true
 			}
 		}
-		Print(analyser, os.Stdout)
 	}
+	return false
+}
+
+func fatal(v ...interface{}) {
+	log.Print(v...)
+	os.Exit(exitError)
 }
 
 func filterNonTestGOFiles(info os.FileInfo) bool {
@@ -1,34 +1,200 @@
 package main
 
 import (
-	"encoding/json"
-	"go/parser"
-	"go/token"
-	"log"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
 )
 
-func TestRouters(t *testing.T) {
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "testdata.go", nil, parser.AllErrors)
+func TestScanIsOrderedAndCached(t *testing.T) {
+	root, err := ioutil.TempDir("", "goserverscan")
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
+	defer os.RemoveAll(root)
 
-	analyser := NewAnalyser(fset)
-	analyser.Run(f)
-	routers := analyser.Routers
+	var dirs []string
+	for _, name := range []string{"a", "b", "c"} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		src := fmt.Sprintf("package %s\n\nimport \"net/http\"\n\nfunc f() { http.Get(%q) }\n", name, name)
+		if err := ioutil.WriteFile(filepath.Join(dir, "f.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, dir)
+	}
 
-	if got, want := len(routers), 4; got != want {
+	c, err := newCache(filepath.Join(root, "cache"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scanner{workers: 3, cache: c, driver: newDriver(audit.Analyzers(nil, nil))}
+
+	for run := 0; run < 2; run++ {
+		results, err := s.Scan(dirs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, res := range results {
+			if got, want := res.Dir, dirs[i]; got != want {
+				t.Fatalf("run %d: got %v, want %v", run, got, want)
+			}
+			if got, want := len(res.OutGoingCalls), 1; got != want {
+				t.Fatalf("run %d: got %v, want %v", run, got, want)
+			}
+		}
+	}
+
+	cached, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(cached), len(dirs); got != want {
 		t.Fatalf("got %v, want %v", got, want)
 	}
+}
+
+func TestScanMergesImportedRoutes(t *testing.T) {
+	root, err := ioutil.TempDir("", "goserverscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(name, src string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := `package api
+
+import "net/http"
+
+func NewRouter() *http.ServeMux { return http.NewServeMux() }
+
+func Register(m *http.ServeMux) {
+	m.HandleFunc(%q, nil)
+}
+`
+	write("go.mod", "module example.com/app\n")
+	write("api/api.go", fmt.Sprintf(api, "/users"))
+	write("main.go", `package main
+
+import (
+	"net/http"
+
+	"example.com/app/api"
+)
+
+func main() {
+	m := http.NewServeMux()
+	api.Register(m)
+	r := api.NewRouter()
+	r.HandleFunc("/extra", nil)
+	http.ListenAndServe(":8080", m)
+}
+`)
+	// The importing package is listed first to check it is still
+	// analysed after the package it imports.
+	dirs := []string{root, filepath.Join(root, "api")}
+
+	c, err := newCache(filepath.Join(root, "cache"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scanner{workers: 2, cache: c, driver: newDriver(audit.Analyzers(nil, nil))}
 
-	for _, r := range analyser.Routers {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", " ")
-		if err := enc.Encode(r); err != nil {
+	paths := func() map[string]bool {
+		results, err := s.Scan(dirs)
+		if err != nil {
 			t.Fatal(err)
 		}
+		found := make(map[string]bool)
+		for _, router := range results[0].Routers {
+			for _, route := range router.Routes {
+				found[route.Path] = true
+			}
+		}
+		return found
+	}
+
+	if found := paths(); !found["/users"] || !found["/extra"] {
+		t.Fatalf("got routes %v, want /users and /extra", found)
+	}
+	// Changing the imported package invalidates the cached results of
+	// the importing one.
+	write("api/api.go", fmt.Sprintf(api, "/admin"))
+	if found := paths(); !found["/admin"] || found["/users"] {
+		t.Fatalf("got routes %v, want /admin without /users", found)
+	}
+}
+
+// TestExitCodes runs main in a subprocess of the test binary, with the
+// arguments in GOSERVERSCAN_ARGS.
+func TestExitCodes(t *testing.T) {
+	if args := os.Getenv("GOSERVERSCAN_ARGS"); args != "" {
+		os.Args = append([]string{"goserverscan"}, strings.Split(args, " ")...)
+		main()
+		return
+	}
+
+	root, err := ioutil.TempDir("", "goserverscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	// A low severity directory listing.
+	src := `package main
+
+import "net/http"
+
+func main() {
+	http.Handle("/files/", http.FileServer(http.Dir("/srv/files")))
+}
+`
+	if err := ioutil.WriteFile(filepath.Join(root, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		args string
+		want int
+	}{
+		{"", exitOK},
+		{"-fail-on info", exitFindings},
+		{"-fail-on low", exitFindings},
+		{"-fail-on medium", exitOK},
+		{"-fail-on low -update-baseline -baseline " + filepath.Join(root, "baseline.json"), exitOK},
+		{"-fail-on low -baseline " + filepath.Join(root, "baseline.json"), exitOK},
+		{"-fail-on severe", exitError},
+		{"-format xml", exitError},
+	} {
+		args := strings.TrimSpace("-cache= -dir " + root + " " + test.args)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExitCodes$")
+		cmd.Env = append(os.Environ(), "GOSERVERSCAN_ARGS="+args)
+		err := cmd.Run()
+		got := exitOK
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			got = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got exit code %d, want %d", test.args, got, test.want)
+		}
 	}
 }
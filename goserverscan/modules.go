@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Module is a Go module found under a scanned root.
+type Module struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+// archiveVersionRE matches the version in the name of source archives
+// such as app-v1.2.3.zip or app@v1.2.3.tar.gz.
+var archiveVersionRE = regexp.MustCompile(`[-_@](v?[0-9]+\.[0-9]+\.[0-9]+[0-9A-Za-z.+-]*?)\.(?i:zip|tar\.gz|tgz)$`)
+
+// findModules returns the modules whose go.mod is under root, innermost
+// first. Versions are read from module directories named path@version
+// as in module zips, or else from the name of archive.
+func findModules(root, archive string) ([]*Module, error) {
+	var modules []*Module
+	err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() || f.Name() != "go.mod" {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(path)
+		m := &Module{Path: modfile.ModulePath(content), Dir: dir}
+		if _, version, found := strings.Cut(filepath.Base(dir), "@"); found {
+			m.Version = version
+		} else if match := archiveVersionRE.FindStringSubmatch(filepath.Base(archive)); match != nil {
+			m.Version = match[1]
+		}
+		modules = append(modules, m)
+		return nil
+	})
+	sort.SliceStable(modules, func(i, j int) bool { return len(modules[i].Dir) > len(modules[j].Dir) })
+	return modules, err
+}
+
+// moduleOf returns the innermost of modules enclosing dir, or nil.
+func moduleOf(modules []*Module, dir string) *Module {
+	for _, m := range modules {
+		if rel, err := filepath.Rel(m.Dir, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return m
+		}
+	}
+	return nil
+}
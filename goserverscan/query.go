@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+// Query selects items of a scan. Queries read
+//
+//	KIND [where COND [and COND]...]
+//
+// with KIND one of the keys of queryFields and COND
+// [not] FIELD [OP VALUE]. OP is = (equals), != (differs), ^= (has
+// prefix), *= (contains) or ~ (matches regexp). A FIELD alone holds
+// when it is true, and fields with several values, as the middleware
+// of routes, match when any of their values does. Values with spaces
+// are double quoted.
+//
+//	routes where path ^= /admin and not authenticated
+//	routes where input = header:X-Forwarded-For
+//	calls where package = billing
+type Query struct {
+	Kind  string
+	Conds []*Cond
+}
+
+type Cond struct {
+	Not   bool
+	Field string
+	Op    string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// commonFields are the fields of every kind of item.
+var commonFields = []string{"file", "line", "dir", "package", "module"}
+
+// queryFields lists the fields of each kind of item, besides
+// commonFields.
+var queryFields = map[string][]string{
+	"routes":   {"path", "method", "middleware", "handler", "input", "router", "authenticated", "websocket"},
+	"handlers": {"name", "kind", "route", "server"},
+	"calls":    {"kind", "code"},
+	"findings": {"rule", "severity", "confidence", "cwe", "message", "code"},
+	"config":   {"source", "name", "default", "flow"},
+	"static":   {"kind", "prefix", "root", "listing", "embed", "served"},
+}
+
+var queryOps = []string{"=", "!=", "^=", "*=", "~"}
+
+// ParseQuery parses q as documented on Query.
+func ParseQuery(q string) (*Query, error) {
+	tokens, err := splitQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	query := &Query{Kind: tokens[0]}
+	fields, ok := queryFields[query.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q (%s)", query.Kind, strings.Join(queryKinds(), ", "))
+	}
+	fields = slices.Concat(fields, commonFields)
+	tokens = tokens[1:]
+	if len(tokens) == 0 {
+		return query, nil
+	}
+	if tokens[0] != "where" {
+		return nil, fmt.Errorf("expected where, got %q", tokens[0])
+	}
+
+	for _, cond := range splitTokens(tokens[1:], "and") {
+		c := &Cond{}
+		if len(cond) > 0 && (cond[0] == "not" || cond[0] == "!") {
+			c.Not, cond = true, cond[1:]
+		}
+		switch len(cond) {
+		case 1:
+			c.Field, c.Op, c.Value = cond[0], "=", "true"
+		case 3:
+			c.Field, c.Op, c.Value = cond[0], cond[1], cond[2]
+		default:
+			return nil, fmt.Errorf("invalid condition %q", strings.Join(cond, " "))
+		}
+		if !slices.Contains(fields, c.Field) {
+			return nil, fmt.Errorf("unknown field %q of %s (%s)", c.Field, query.Kind, strings.Join(fields, ", "))
+		}
+		if !slices.Contains(queryOps, c.Op) {
+			return nil, fmt.Errorf("unknown operator %q (%s)", c.Op, strings.Join(queryOps, " "))
+		}
+		if c.Op == "~" {
+			if c.re, err = regexp.Compile(c.Value); err != nil {
+				return nil, err
+			}
+		}
+		query.Conds = append(query.Conds, c)
+	}
+	return query, nil
+}
+
+func queryKinds() (kinds []string) {
+	for kind := range queryFields {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// splitQuery splits q on spaces, keeping double quoted strings whole.
+func splitQuery(q string) (tokens []string, err error) {
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		if q[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(q)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string in %s", q)
+			}
+			unquoted, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, unquoted)
+			q = q[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(q, " \t")
+		if end < 0 {
+			end = len(q)
+		}
+		tokens = append(tokens, q[:end])
+		q = q[end:]
+	}
+	return tokens, nil
+}
+
+func splitTokens(tokens []string, sep string) (groups [][]string) {
+	var group []string
+	for _, t := range tokens {
+		if t == sep {
+			groups = append(groups, group)
+			group = nil
+			continue
+		}
+		group = append(group, t)
+	}
+	return append(groups, group)
+}
+
+// match reports whether any of values matches c, or none for negated
+// conditions and !=.
+func (c *Cond) match(values []string) bool {
+	op, not := c.Op, c.Not
+	if op == "!=" {
+		op, not = "=", !not
+	}
+	for _, v := range values {
+		var matched bool
+		switch op {
+		case "=":
+			matched = strings.EqualFold(v, c.Value)
+		case "^=":
+			matched = strings.HasPrefix(v, c.Value)
+		case "*=":
+			matched = strings.Contains(v, c.Value)
+		case "~":
+			matched = c.re.MatchString(v)
+		}
+		if matched {
+			return !not
+		}
+	}
+	return not
+}
+
+// queryItem is an item of a scan with the values of its fields, and
+// the line printing it.
+type queryItem struct {
+	fields map[string][]string
+	line   string
+	value  interface{}
+}
+
+// Run returns the items of r selected by q.
+func (q *Query) Run(r *Report) (items []queryItem) {
+	for _, item := range reportItems(q.Kind, r) {
+		selected := true
+		for _, c := range q.Conds {
+			if !c.match(item.fields[c.Field]) {
+				selected = false
+				break
+			}
+		}
+		if selected {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func reportItems(kind string, r *Report) (items []queryItem) {
+	for _, res := range r.Results {
+		add := func(s audit.Snippet, line string, value interface{}, fields map[string][]string) {
+			fields["file"] = []string{s.Filename}
+			fields["line"] = []string{strconv.Itoa(s.Line)}
+			fields["dir"] = []string{res.Dir}
+			fields["package"] = []string{filepath.Base(res.Dir)}
+			fields["module"] = []string{res.Module}
+			items = append(items, queryItem{fields: fields, line: line, value: value})
+		}
+		switch kind {
+		case "routes":
+			for _, router := range res.Routers {
+				for _, route := range router.Routes {
+					var inputs []string
+					for _, in := range route.Inputs {
+						inputs = append(inputs, in.String())
+					}
+					add(route.Snippet, fmt.Sprintf("Route %s%s", route.Snippet, routeDetails(route)), route, map[string][]string{
+						"path":          {route.Path},
+						"method":        route.Methods,
+						"middleware":    append(append([]string(nil), router.Middleware...), route.Middleware...),
+						"handler":       {route.Handler},
+						"input":         inputs,
+						"router":        {router.Kind},
+						"authenticated": {strconv.FormatBool(route.Authenticated)},
+						"websocket":     {strconv.FormatBool(route.WebSocket)},
+					})
+				}
+			}
+		case "handlers":
+			for _, h := range res.Handlers {
+				add(h.Snippet, fmt.Sprintf("Handler %s%s", h.Snippet, handlerDetails(h)), h, map[string][]string{
+					"name":   {h.Name},
+					"kind":   {h.Kind},
+					"route":  h.Routes,
+					"server": h.Servers,
+				})
+			}
+		case "calls":
+			for _, c := range res.OutGoingCalls {
+				add(c.Snippet, fmt.Sprintf("OutGoingCall %s '%s'", c, c.Code), c, map[string][]string{
+					"kind": {c.Kind},
+					"code": {c.Code},
+				})
+			}
+		case "findings":
+			for _, f := range res.Findings {
+				add(f.Snippet, fmt.Sprintf("Finding %s", f), f, map[string][]string{
+					"rule":       {f.RuleID},
+					"severity":   {string(f.Severity)},
+					"confidence": {string(f.Confidence)},
+					"cwe":        {f.CWE},
+					"message":    {f.Message},
+					"code":       {f.Code},
+				})
+			}
+		case "config":
+			for _, k := range res.Config {
+				add(k.Snippet, fmt.Sprintf("Config %s%s", k.Snippet, configDetails(k)), k, map[string][]string{
+					"source":  {k.Source},
+					"name":    {k.Name},
+					"default": {k.Default},
+					"flow":    k.Flows,
+				})
+			}
+		case "static":
+			for _, m := range res.Static {
+				add(m.Snippet, fmt.Sprintf("Static %s%s", m.Snippet, staticDetails(m)), m, map[string][]string{
+					"kind":    {m.Kind},
+					"prefix":  {m.Prefix},
+					"root":    {m.Root},
+					"listing": {strconv.FormatBool(m.Listing)},
+					"embed":   {strconv.FormatBool(m.Embed)},
+					"served":  m.Files,
+				})
+			}
+		}
+	}
+	return items
+}
+
+// PrintQuery writes the items selected by q, one per line followed by
+// their count, or as a JSON array.
+func PrintQuery(items []queryItem, asJSON bool, w io.Writer) error {
+	if asJSON {
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			values[i] = item.value
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", " ")
+		return enc.Encode(values)
+	}
+	for _, item := range items {
+		fmt.Fprintln(w, item.line)
+	}
+	_, err := fmt.Fprintf(w, "%d results\n", len(items))
+	return err
+}
+
+// Explore answers the queries read line by line from r, writing the
+// results to w and prompts and errors to prompt.
+func Explore(report *Report, r io.Reader, w, prompt io.Writer) error {
+	sc := bufio.NewScanner(r)
+	for fmt.Fprint(prompt, "> "); sc.Scan(); fmt.Fprint(prompt, "> ") {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if line == "exit" || line == "quit" {
+			break
+		}
+		q, err := ParseQuery(line)
+		if err != nil {
+			fmt.Fprintln(prompt, err)
+			continue
+		}
+		if err := PrintQuery(q.Run(report), false, w); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func TestQuery(t *testing.T) {
+	route := func(path string, auth bool, inputs ...*audit.Input) *audit.Route {
+		return &audit.Route{Snippet: audit.Snippet{Filename: "main.go"}, Path: path, Authenticated: auth, Inputs: inputs}
+	}
+	report := &Report{Results: []*Result{{
+		Dir: "app/admin",
+		Routers: []*audit.Router{{
+			Kind:       "net/http",
+			Middleware: []string{"logging"},
+			Routes: []*audit.Route{
+				route("/admin/users", true),
+				route("/admin/export", false, &audit.Input{In: audit.InHeader, Name: "X-Forwarded-For"}),
+				route("/health", false),
+			},
+		}},
+	}, {
+		Dir: "app/billing",
+		OutGoingCalls: []*audit.OutGoingCall{
+			{Snippet: audit.Snippet{Code: `http.Post(stripeURL, "", nil)`}, Kind: "http.Post"},
+		},
+	}}}
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"routes where path ^= /admin and not authenticated", []string{"/admin/export"}},
+		{"routes where input = header:X-Forwarded-For", []string{"/admin/export"}},
+		{"routes where middleware = logging and path != /health", []string{"/admin/users", "/admin/export"}},
+		{`routes where path ~ "^/(health|admin/users)$"`, []string{"/admin/users", "/health"}},
+		{"calls where package = billing", []string{`http.Post(stripeURL, "", nil)`}},
+		{"calls where package = admin", nil},
+	} {
+		q, err := ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range q.Run(report) {
+			switch v := item.value.(type) {
+			case *audit.Route:
+				got = append(got, v.Path)
+			case *audit.OutGoingCall:
+				got = append(got, v.Code)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.query, got, test.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "users", "routes having path", "routes where owner = me", "routes where path > /", "routes where path = /a b"} {
+		if _, err := ParseQuery(invalid); err == nil {
+			t.Errorf("%q: want error", invalid)
+		}
+	}
+}
+
+func TestExplore(t *testing.T) {
+	report := &Report{Results: []*Result{{
+		Findings: []*audit.Finding{{RuleID: "weak-hash", Severity: audit.SeverityMedium}},
+	}}}
+	var out, prompt bytes.Buffer
+	if err := Explore(report, strings.NewReader("findings where severity = medium\nfindings where owner = me\nexit\nfindings\n"), &out, &prompt); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.HasSuffix(got, "1 results\n") {
+		t.Errorf("got %q, want 1 result", got)
+	}
+	if got, want := prompt.String(), "> > unknown field \"owner\""; !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+}
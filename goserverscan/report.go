@@ -3,13 +3,188 @@ package main
 import (
 	"fmt"
 	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/simcap/auditools/goserverscan/audit"
 )
 
-func Print(a *analyser, w io.Writer) {
-	for _, r := range a.Routers {
-		fmt.Fprintf(w, "Router %s\n", r.Snippet)
+// PrintResults prints results, heading the results of each module
+// with its path and version.
+func PrintResults(results []*Result, w io.Writer) {
+	var module string
+	for _, res := range results {
+		if m := moduleName(res); m != "" && m != module {
+			module = m
+			fmt.Fprintf(w, "Module %s (%s)\n", module, res.Root)
+		}
+		Print(res, w)
+	}
+}
+
+// moduleName returns the path@version of the module of res.
+func moduleName(res *Result) string {
+	return strings.TrimSuffix(res.Module+"@"+res.Version, "@")
+}
+
+func Print(res *Result, w io.Writer) {
+	for _, r := range res.Routers {
+		fmt.Fprintf(w, "Router %s%s\n", r.Snippet, routerDetails(r))
 		for _, route := range r.Routes {
-			fmt.Fprintf(w, "\tRoute %s\n", route.Snippet)
+			fmt.Fprintf(w, "\tRoute %s%s\n", route.Snippet, routeDetails(route))
 		}
 	}
+	for _, h := range res.Handlers {
+		fmt.Fprintf(w, "Handler %s%s\n", h.Snippet, handlerDetails(h))
+	}
+	for _, k := range res.Config {
+		fmt.Fprintf(w, "Config %s%s\n", k.Snippet, configDetails(k))
+	}
+	for _, m := range res.Static {
+		fmt.Fprintf(w, "Static %s%s\n", m.Snippet, staticDetails(m))
+	}
+	for _, f := range res.Findings {
+		fmt.Fprintf(w, "Finding %s\n", f)
+	}
+}
+
+func handlerDetails(h *audit.Handler) string {
+	d := []string{h.Kind + ": " + h.Name}
+	if len(h.Routes) > 0 {
+		d = append(d, "routes: "+strings.Join(h.Routes, ","))
+	}
+	if len(h.Servers) > 0 {
+		d = append(d, "servers: "+strings.Join(h.Servers, ","))
+	}
+	return " (" + strings.Join(d, "; ") + ")"
+}
+
+func configDetails(k *audit.ConfigKey) string {
+	d := []string{k.Source + ": " + k.Name}
+	if k.Default != "" {
+		d = append(d, "default: "+k.Default)
+	}
+	if len(k.Flows) > 0 {
+		d = append(d, "flows: "+strings.Join(k.Flows, ","))
+	}
+	return " (" + strings.Join(d, "; ") + ")"
+}
+
+func staticDetails(m *audit.StaticMount) string {
+	d := []string{"kind: " + m.Kind}
+	if m.Prefix != "" {
+		d = append(d, "prefix: "+m.Prefix)
+	}
+	root := "root: " + m.Root
+	if m.Embed {
+		root += " (embedded)"
+	}
+	d = append(d, root)
+	if m.Listing {
+		d = append(d, "listing")
+	}
+	if len(m.Files) > 0 {
+		d = append(d, "serves: "+strings.Join(m.Files, ","))
+	}
+	return " (" + strings.Join(d, "; ") + ")"
+}
+
+func routerDetails(r *audit.Router) string {
+	d := []string{"kind: " + r.Kind}
+	if r.Mount != "" {
+		d = append(d, "mount: "+r.Mount)
+	}
+	if len(r.Middleware) > 0 {
+		d = append(d, "middleware: "+strings.Join(r.Middleware, ","))
+	}
+	return " (" + strings.Join(d, "; ") + ")"
+}
+
+func routeDetails(r *audit.Route) string {
+	var d []string
+	if !strings.Contains(r.Code, r.Path) {
+		d = append(d, r.Path)
+	}
+	if len(r.Methods) > 0 {
+		d = append(d, "methods: "+strings.Join(r.Methods, ","))
+	}
+	if len(r.Middleware) > 0 {
+		d = append(d, "middleware: "+strings.Join(r.Middleware, ","))
+	}
+	if len(r.Inputs) > 0 {
+		inputs := make([]string, len(r.Inputs))
+		for i, in := range r.Inputs {
+			inputs[i] = in.String()
+		}
+		d = append(d, "inputs: "+strings.Join(inputs, ","))
+	}
+	if r.Authenticated {
+		d = append(d, "authenticated")
+	}
+	if r.WebSocket {
+		d = append(d, "websocket")
+	}
+	if len(d) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(d, "; ") + ")"
+}
+
+// PrintSummary writes a table counting findings by rule and severity.
+// Findings of a severity not in audit.Severities, as from older scans,
+// are counted in an unknown column.
+func PrintSummary(results []*Result, w io.Writer) {
+	const unknown audit.Severity = "unknown"
+	var hasUnknown bool
+	counts := make(map[string]map[audit.Severity]int)
+	var ids []string
+	for _, res := range results {
+		for _, f := range res.Findings {
+			if counts[f.RuleID] == nil {
+				counts[f.RuleID] = make(map[audit.Severity]int)
+				ids = append(ids, f.RuleID)
+			}
+			sev := f.Severity
+			if !slices.Contains(audit.Severities, sev) {
+				sev, hasUnknown = unknown, true
+			}
+			counts[f.RuleID][sev]++
+		}
+	}
+	sort.Strings(ids)
+	severities := audit.Severities
+	if hasUnknown {
+		severities = append(append([]audit.Severity{}, audit.Severities...), unknown)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(tw, "RULE\t")
+	for _, sev := range severities {
+		fmt.Fprintf(tw, "%s\t", sev)
+	}
+	fmt.Fprint(tw, "total\t\n")
+
+	totals := make(map[audit.Severity]int)
+	var total int
+	for _, id := range ids {
+		fmt.Fprintf(tw, "%s\t", id)
+		var sum int
+		for _, sev := range severities {
+			n := counts[id][sev]
+			fmt.Fprintf(tw, "%d\t", n)
+			totals[sev] += n
+			sum += n
+		}
+		fmt.Fprintf(tw, "%d\t\n", sum)
+		total += sum
+	}
+
+	fmt.Fprint(tw, "TOTAL\t")
+	for _, sev := range severities {
+		fmt.Fprintf(tw, "%d\t", totals[sev])
+	}
+	fmt.Fprintf(tw, "%d\t\n", total)
+	tw.Flush()
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func TestPrintSummary(t *testing.T) {
+	results := []*Result{{
+		Findings: []*audit.Finding{
+			{RuleID: "sql-from-request", Severity: audit.SeverityHigh},
+			{RuleID: "sql-from-request", Severity: audit.SeverityHigh},
+			{RuleID: "weak-hash", Severity: audit.SeverityLow},
+		},
+	}, {
+		Findings: []*audit.Finding{
+			{RuleID: "weak-hash", Severity: "severe"},
+		},
+	}}
+
+	var buf bytes.Buffer
+	PrintSummary(results, &buf)
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"RULE info low medium high critical unknown total",
+		"sql-from-request 0 0 0 2 0 0 2",
+		"weak-hash 0 1 0 0 0 1 2",
+		"TOTAL 0 1 0 2 0 1 4",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestHasFindingsAbove(t *testing.T) {
+	results := []*Result{{
+		Findings: []*audit.Finding{
+			{RuleID: "weak-hash", Severity: audit.SeverityLow},
+			{RuleID: "sql-from-request", Severity: audit.SeverityMedium},
+			{RuleID: "old", Severity: "severe"},
+		},
+	}}
+	for _, test := range []struct {
+		threshold audit.Severity
+		want      bool
+	}{
+		{audit.SeverityInfo, true},
+		{audit.SeverityLow, true},
+		{audit.SeverityMedium, true},
+		{audit.SeverityHigh, false},
+		{audit.SeverityCritical, false},
+	} {
+		if got := hasFindingsAbove(results, test.threshold); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.threshold, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+// Result holds what the analyzers found in a single directory, the
+// directory or archive scanned to find it and its enclosing module.
+type Result struct {
+	Dir           string
+	Root          string
+	Module        string
+	Version       string
+	Routers       []*audit.Router
+	Handlers      []*audit.Handler
+	OutGoingCalls []*audit.OutGoingCall
+	Config        []*audit.ConfigKey
+	Static        []*audit.StaticMount
+	Findings      []*audit.Finding
+}
+
+type scanner struct {
+	workers int
+	cache   *cache
+	driver  *driver
+}
+
+// ScanRoots scans each of roots, directories or source archives, and
+// tags results with their module. Archives are unpacked to temporary
+// sandboxes, scanned without caching, and their results located by
+// paths under the archive.
+func (s *scanner) ScanRoots(roots []string) ([]*Result, error) {
+	var all []*Result
+	for _, root := range roots {
+		var (
+			results []*Result
+			err     error
+		)
+		if isArchive(root) {
+			results, err = s.scanArchive(root)
+		} else {
+			results, err = s.ScanRoot(root)
+			if err == nil {
+				err = tagModules(root, "", results)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range results {
+			res.Root = root
+		}
+		all = append(all, results...)
+	}
+	return all, nil
+}
+
+func (s *scanner) scanArchive(archive string) ([]*Result, error) {
+	dir, cleanup, err := unpackArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	uncached := *s
+	uncached.cache = nil
+	results, err := uncached.ScanRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := tagModules(dir, archive, results); err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		res.relocate(dir, archive)
+	}
+	return results, nil
+}
+
+// tagModules sets the module of results from the go.mod files found
+// under root.
+func tagModules(root, archive string, results []*Result) error {
+	modules, err := findModules(root, archive)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if m := moduleOf(modules, res.Dir); m != nil {
+			res.Module, res.Version = m.Path, m.Version
+		}
+	}
+	return nil
+}
+
+// relocate rewrites the paths of res under dir to the same paths under
+// to.
+func (res *Result) relocate(dir, to string) {
+	move := func(path string) string {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join(to, rel)
+		}
+		return path
+	}
+	res.Dir = move(res.Dir)
+	for _, r := range res.Routers {
+		r.Filename = move(r.Filename)
+		for _, route := range r.Routes {
+			route.Filename = move(route.Filename)
+			route.HandlerLocation = move(route.HandlerLocation)
+		}
+	}
+	for _, h := range res.Handlers {
+		h.Filename = move(h.Filename)
+	}
+	for _, c := range res.OutGoingCalls {
+		c.Filename = move(c.Filename)
+	}
+	for _, k := range res.Config {
+		k.Filename = move(k.Filename)
+	}
+	for _, m := range res.Static {
+		m.Filename = move(m.Filename)
+	}
+	for _, f := range res.Findings {
+		f.Filename = move(f.Filename)
+	}
+}
+
+// ScanRoot scans root and all the directories below it.
+func (s *scanner) ScanRoot(root string) ([]*Result, error) {
+	var dirs []string
+	if err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s.Scan(dirs)
+}
+
+// Scan parses and analyses dirs using a bounded pool of workers.
+// Directories are analysed after the scanned directories they import,
+// with their types and facts, so that routers registered across
+// packages are merged. Results are returned in the same order as dirs
+// whatever the order in which workers complete.
+func (s *scanner) Scan(dirs []string) ([]*Result, error) {
+	workers := s.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	g, err := newImportGraph(dirs)
+	if err != nil {
+		return nil, err
+	}
+	order := g.order()
+
+	results := make([]*Result, len(dirs))
+	keys := make([]string, len(dirs))
+	analyse := make([]bool, len(dirs))
+	for _, j := range order {
+		if s.cache == nil {
+			analyse[j] = true
+			continue
+		}
+		var deps []string
+		for _, path := range sortedImports(g.imports[j]) {
+			deps = append(deps, keys[g.imports[j][path]])
+		}
+		if keys[j], err = s.cache.key(dirs[j], deps); err != nil {
+			return nil, err
+		}
+		if res, ok := s.cache.get(keys[j]); ok {
+			results[j] = res
+		} else {
+			analyse[j] = true
+		}
+	}
+	// Directories analysed need the types and facts of the ones they
+	// import, even when cached.
+	for k := len(order) - 1; k >= 0; k-- {
+		if j := order[k]; analyse[j] {
+			for _, dep := range g.imports[j] {
+				analyse[dep] = true
+			}
+		}
+	}
+
+	facts := newFactStore()
+	pkgs := make([]*types.Package, len(dirs))
+	errs := make([]error, len(dirs))
+	done := make([]chan struct{}, len(dirs))
+	for j := range done {
+		done[j] = make(chan struct{})
+	}
+
+	// Jobs are sent in import order, so the directories a job waits for
+	// are already taken by other workers.
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				deps := make(map[string]*types.Package)
+				for path, dep := range g.imports[j] {
+					<-done[dep]
+					if pkgs[dep] != nil {
+						deps[path] = pkgs[dep]
+					}
+				}
+				results[j], pkgs[j], errs[j] = s.scanDir(dirs[j], g.paths[j], deps, facts, keys[j])
+				close(done[j])
+			}
+		}()
+	}
+	for _, j := range order {
+		if analyse[j] {
+			jobs <- j
+		} else {
+			close(done[j])
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// scanDir analyses the packages of dir, imported as path, and caches
+// the result under key. It returns the package imported by others,
+// the first one that is not a command.
+func (s *scanner) scanDir(dir, path string, deps map[string]*types.Package, facts *factStore, key string) (*Result, *types.Package, error) {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, dir, filterNonTestGOFiles, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := &Result{Dir: dir}
+	var imported *types.Package
+	for _, name := range sortedNames(packages) {
+		pkg, err := s.driver.Run(fset, path, sortedFiles(packages[name]), deps, facts, res)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", dir, err)
+		}
+		if imported == nil || imported.Name() == "main" {
+			imported = pkg
+		}
+	}
+
+	if s.cache != nil {
+		if err := s.cache.put(key, res); err != nil {
+			return nil, nil, err
+		}
+	}
+	return res, imported, nil
+}
+
+func sortedNames(packages map[string]*ast.Package) (names []string) {
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedFiles(pkg *ast.Package) (files []*ast.File) {
+	var names []string
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		files = append(files, pkg.Files[name])
+	}
+	return files
+}
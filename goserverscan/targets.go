@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+// Target is a request template for a route, as written with -format
+// targets to seed dynamic scanners. Path variables and parameters are
+// left as {name} placeholders and JSON bodies hold zero values.
+type Target struct {
+	Method      string
+	URL         string
+	Headers     map[string]string `json:",omitempty"`
+	Cookies     []string          `json:",omitempty"`
+	ContentType string            `json:",omitempty"`
+	Body        string            `json:",omitempty"`
+	Route       string
+	LoginForm   *LoginForm `json:",omitempty"`
+}
+
+// LoginForm is the form of login routes as read by loginpass
+// -form-file.
+type LoginForm struct {
+	URL         string
+	ContentType string
+	Username    string
+	Password    string
+	TokenName   string
+	ExtraInputs []LoginInput
+}
+
+type LoginInput struct {
+	Name, Value string
+}
+
+var (
+	// pathPlaceholderRE matches the variables of route paths to
+	// normalize: {id:[0-9]+}, {path...} and {$}.
+	pathPlaceholderRE = regexp.MustCompile(`\{([^}:.]*)[^}]*\}`)
+
+	loginPathRE = regexp.MustCompile(`(?i)log_?in|sign_?in|session|auth`)
+	usernameRE  = regexp.MustCompile(`(?i)user|login|mail|name`)
+	passwordRE  = regexp.MustCompile(`(?i)pass`)
+	csrfTokenRE = regexp.MustCompile(`(?i)csrf|xsrf|token`)
+)
+
+const graphQLQuery = `{"query":""}`
+
+// NewTargets returns the request templates of the routes of results
+// on baseURL. gRPC services are left out, and GraphQL servers get a
+// single query template per endpoint.
+func NewTargets(baseURL string, results []*Result) []*Target {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	var targets []*Target
+	for _, res := range results {
+		for _, router := range res.Routers {
+			switch {
+			case router.Kind == "grpc":
+				continue
+			case strings.HasPrefix(router.Kind, "graphql"):
+				if len(router.Routes) > 0 {
+					targets = append(targets, &Target{
+						Method:      "POST",
+						URL:         baseURL + router.Mount,
+						ContentType: "application/json",
+						Body:        graphQLQuery,
+						Route:       fmt.Sprintf("%s:%d", router.Filename, router.Line),
+					})
+				}
+				continue
+			}
+			for _, route := range router.Routes {
+				targets = append(targets, routeTargets(baseURL, route)...)
+			}
+		}
+	}
+	return targets
+}
+
+func routeTargets(baseURL string, route *audit.Route) (targets []*Target) {
+	path := pathPlaceholderRE.ReplaceAllStringFunc(route.Path, func(v string) string {
+		name := pathPlaceholderRE.FindStringSubmatch(v)[1]
+		if name == "" {
+			return ""
+		}
+		return "{" + name + "}"
+	})
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	var (
+		query, form url.Values
+		body        = make(map[string]interface{})
+		headers     map[string]string
+		cookies     []string
+		password    bool
+	)
+	for _, in := range route.Inputs {
+		switch in.In {
+		case audit.InQuery:
+			query = appendValue(query, in.Name)
+		case audit.InForm:
+			form = appendValue(form, in.Name)
+		case audit.InHeader:
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[in.Name] = placeholder(in.Name)
+		case audit.InCookie:
+			cookies = append(cookies, in.Name+"="+placeholder(in.Name))
+		case audit.InBody:
+			body[in.Name] = sampleValue(in.Type)
+		}
+		if in.In != audit.InPath && passwordRE.MatchString(in.Name) {
+			password = true
+		}
+	}
+
+	t := &Target{
+		URL:     baseURL + path,
+		Headers: headers,
+		Cookies: cookies,
+		Route:   fmt.Sprintf("%s:%d", route.Filename, route.Line),
+	}
+	if len(query) > 0 {
+		t.URL += "?" + encodePlaceholders(query)
+	}
+	switch {
+	case len(body) > 0:
+		b, _ := json.Marshal(body)
+		t.ContentType, t.Body = "application/json", string(b)
+	case len(form) > 0:
+		t.ContentType, t.Body = "application/x-www-form-urlencoded", encodePlaceholders(form)
+	}
+	if password && loginPathRE.MatchString(route.Path) {
+		t.LoginForm = loginForm(t.URL, t.ContentType, route.Inputs)
+	}
+
+	methods := route.Methods
+	if len(methods) == 0 {
+		methods = []string{"GET"}
+		if t.Body != "" {
+			methods = []string{"POST"}
+		}
+	}
+	for _, m := range methods {
+		target := *t
+		target.Method = strings.ToUpper(m)
+		targets = append(targets, &target)
+	}
+	return targets
+}
+
+// loginForm guesses the username, password and CSRF token inputs of
+// a login route.
+func loginForm(targetURL, contentType string, inputs []*audit.Input) *LoginForm {
+	f := &LoginForm{URL: strings.SplitN(targetURL, "?", 2)[0], ContentType: contentType}
+	for _, in := range inputs {
+		if in.In != audit.InForm && in.In != audit.InBody && in.In != audit.InQuery {
+			continue
+		}
+		switch {
+		case f.Password == "" && passwordRE.MatchString(in.Name):
+			f.Password = in.Name
+		case f.TokenName == "" && csrfTokenRE.MatchString(in.Name):
+			f.TokenName = in.Name
+		case f.Username == "" && usernameRE.MatchString(in.Name):
+			f.Username = in.Name
+		default:
+			f.ExtraInputs = append(f.ExtraInputs, LoginInput{Name: in.Name})
+		}
+	}
+	return f
+}
+
+func appendValue(values url.Values, name string) url.Values {
+	if values == nil {
+		values = make(url.Values)
+	}
+	values.Set(name, placeholder(name))
+	return values
+}
+
+func placeholder(name string) string { return "{" + name + "}" }
+
+// encodePlaceholders encodes values keeping the braces of their
+// placeholders readable.
+func encodePlaceholders(values url.Values) string {
+	return strings.NewReplacer("%7B", "{", "%7D", "}").Replace(values.Encode())
+}
+
+// sampleValue returns the zero value of the JSON type typ.
+func sampleValue(typ string) interface{} {
+	switch typ {
+	case "string":
+		return ""
+	case "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []interface{}{}
+	case "object":
+		return map[string]interface{}{}
+	}
+	return nil
+}
+
+// WriteTargets writes targets as an indented JSON array.
+func WriteTargets(targets []*Target, w io.Writer) error {
+	if targets == nil {
+		targets = []*Target{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(targets)
+}
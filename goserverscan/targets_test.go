@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/simcap/auditools/goserverscan/audit"
+)
+
+func TestNewTargets(t *testing.T) {
+	results := []*Result{{
+		Routers: []*audit.Router{{
+			Kind: "gorilla/mux",
+			Routes: []*audit.Route{{
+				Snippet: audit.Snippet{Filename: "main.go", Line: 10},
+				Path:    "/users/{id:[0-9]+}/files/{path...}",
+				Methods: []string{"GET", "DELETE"},
+				Inputs: []*audit.Input{
+					{In: audit.InPath, Name: "id", Type: "string"},
+					{In: audit.InQuery, Name: "sort", Type: "string"},
+					{In: audit.InHeader, Name: "X-Request-ID", Type: "string"},
+				},
+			}, {
+				Snippet: audit.Snippet{Filename: "main.go", Line: 12},
+				Path:    "/login",
+				Inputs: []*audit.Input{
+					{In: audit.InBody, Name: "username", Type: "string"},
+					{In: audit.InBody, Name: "password", Type: "string"},
+					{In: audit.InBody, Name: "remember", Type: "boolean"},
+				},
+			}},
+		}, {
+			Kind:   "grpc",
+			Routes: []*audit.Route{{Path: "/Greeter/SayHello"}},
+		}, {
+			Snippet: audit.Snippet{Filename: "main.go", Line: 20},
+			Kind:    "graphql/gqlgen",
+			Mount:   "/query",
+			Routes:  []*audit.Route{{Path: "Query.User"}},
+		}},
+	}}
+
+	got := NewTargets("https://example.com/", results)
+	headers := map[string]string{"X-Request-ID": "{X-Request-ID}"}
+	want := []*Target{
+		{Method: "GET", URL: "https://example.com/users/{id}/files/{path}?sort={sort}", Headers: headers, Route: "main.go:10"},
+		{Method: "DELETE", URL: "https://example.com/users/{id}/files/{path}?sort={sort}", Headers: headers, Route: "main.go:10"},
+		{
+			Method:      "POST",
+			URL:         "https://example.com/login",
+			ContentType: "application/json",
+			Body:        `{"password":"","remember":false,"username":""}`,
+			Route:       "main.go:12",
+			LoginForm: &LoginForm{
+				URL:         "https://example.com/login",
+				ContentType: "application/json",
+				Username:    "username",
+				Password:    "password",
+				ExtraInputs: []LoginInput{{Name: "remember"}},
+			},
+		},
+		{Method: "POST", URL: "https://example.com/query", ContentType: "application/json", Body: graphQLQuery, Route: "main.go:20"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, target := range got {
+			t.Logf("%+v", target)
+		}
+		t.Fatal("unexpected targets")
+	}
+}
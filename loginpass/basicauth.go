@@ -1,29 +1,35 @@
-package main
+package loginpass
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/http/httptrace"
-	"net/http/httputil"
 	"strings"
-	"time"
 )
 
 type basicAuthPoster struct {
-	url string
+	url  string
+	opts *options
 }
 
-func (ba *basicAuthPoster) Try(username, pass string) (*Signature, error) {
-	req, err := http.NewRequest("GET", ba.url, nil)
+// NewBasicAuthPoster returns a Poster trying credentials with basic
+// authentication on url.
+func NewBasicAuthPoster(url string, opts ...Option) Poster {
+	return &basicAuthPoster{url: url, opts: newOptions(opts)}
+}
+
+func (ba *basicAuthPoster) Try(ctx context.Context, username, pass string) (*Signature, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", ba.url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.SetBasicAuth(username, pass)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:63.0) Gecko/20100101 Firefox/63.0")
+	req.Header.Set("User-Agent", userAgent)
 
-	sig, resp, err := sendRequest(req)
+	sig, resp, err := ba.opts.send(req)
+	if err != nil {
+		return nil, err
+	}
 	sig.Username = username
 
 	canonical := http.CanonicalHeaderKey("WWW-Authenticate")
@@ -31,55 +37,5 @@ func (ba *basicAuthPoster) Try(username, pass string) (*Signature, error) {
 		return nil, fmt.Errorf("Not basic authentication as %s does not respond as basic auth (header %s=%q)", ba.url, canonical, header)
 	}
 
-	return sig, err
-}
-
-func sendRequest(req *http.Request) (*Signature, *http.Response, error) {
-	dump, err := httputil.DumpRequestOut(req, true)
-	if err != nil {
-		return nil, nil, err
-	}
-	verbose("------------------------------------------------\n%s\n--------------------------------------------", dump)
-
-	var serverStart, serverDone time.Time
-	trace := &httptrace.ClientTrace{
-		WroteRequest:         func(info httptrace.WroteRequestInfo) { serverStart = time.Now() },
-		GotFirstResponseByte: func() { serverDone = time.Now() },
-	}
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-
-	var redirectCount int
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			redirectCount++
-			verbose("Redirecting to %s (%d)", req.URL, len(via))
-			if len(via) >= 10 {
-				return errors.New("stopped after 10 redirects")
-			}
-			return nil
-		},
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, resp, err
-	}
-
-	verbose("-> Response %s\n", resp.Status)
-
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, resp, err
-	}
-	if len(b) < 200 {
-		verbose("----------- Response Body ---------\n%s\n-----------------------------", b)
-	}
-
-	sig := &Signature{
-		RedirectCount:        redirectCount,
-		StatusCode:           resp.StatusCode,
-		ResponseSize:         len(b),
-		ServerProcessingTime: serverDone.Sub(serverStart),
-	}
-
-	return sig, resp, nil
+	return sig, nil
 }
@@ -1,69 +1,173 @@
-package main
+package loginpass
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"math/rand"
-	"os"
+	"sync"
 	"time"
 )
 
+// userAgent is sent with every try.
+const userAgent = "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:63.0) Gecko/20100101 Firefox/63.0"
+
 // Candidater uses a Poster to try username/pass combination.
 // It the compares each try against a base negative response signature
 // calculated at the start of a run.
 //
+// Tries are run by a pool of workers, each waiting between its tries.
+// When rate limited, the requests of tries are spaced by a token bucket
+// shared with the poster.
+//
 // It then output potential candidates of valid credentials
 type Candidater struct {
-	poster           Poster
-	waitTime, jitter int
-	usernames        []string
-	passwords        []string
-	candidates       []string
-	logger           *log.Logger
+	poster     Poster
+	opts       *options
+	candidates []string
 }
 
-func NewCandidater(poster Poster) *Candidater {
+// NewCandidater returns a Candidater trying with poster the usernames
+// and passwords set with WithUsernames and WithPasswords.
+func NewCandidater(poster Poster, opts ...Option) *Candidater {
 	return &Candidater{
-		poster:   poster,
-		waitTime: 5,
-		jitter:   5,
-		logger:   log.New(os.Stdout, "", log.Ltime),
+		poster: poster,
+		opts:   newOptions(opts),
 	}
 }
 
-func (c *Candidater) EstimatedMaxTime() int {
-	return (len(c.usernames) * len(c.passwords) * (c.waitTime + c.jitter)) / 60
+// requestsPerTry is implemented by posters sending more than one
+// request per try.
+type requestsPerTry interface {
+	requestsPerTry() int
+}
+
+// EstimatedMaxTime returns the time a run takes when every wait lasts
+// its maximum, bound by the waits of workers or by the rate limit,
+// whichever is slower. The time taken by requests is not accounted.
+func (c *Candidater) EstimatedMaxTime() time.Duration {
+	tries := len(c.opts.usernames) * len(c.opts.passwords)
+	perWorker := (tries + c.opts.workers - 1) / c.opts.workers
+	var estimated time.Duration
+	if perWorker > 1 {
+		estimated = time.Duration(perWorker-1) * (c.opts.wait + c.opts.jitter)
+	}
+	if c.opts.limiter != nil {
+		requests := 1
+		if p, ok := c.poster.(requestsPerTry); ok {
+			requests = p.requestsPerTry()
+		}
+		// The base signature is a try too.
+		if limited := c.opts.limiter.duration((tries + 1) * requests); limited > estimated {
+			estimated = limited
+		}
+	}
+	return estimated
 }
 
-func (c *Candidater) Run() error {
-	baseSig, err := c.poster.Try(randString(8), randString(13))
+// Candidates returns the username|pass pairs found by Run.
+func (c *Candidater) Candidates() []string {
+	return c.candidates
+}
+
+type pair struct {
+	user, pass string
+}
+
+// Run tries all the username/pass pairs and keeps the candidates, in
+// the order of usernames then passwords. It stops at the first error
+// or when ctx is done, cancelling the tries in progress.
+func (c *Candidater) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	baseSig, err := c.poster.Try(ctx, randString(8), randString(13))
 	if err != nil {
 		return err
 	}
 
-	c.logger.Printf("base signature %s", baseSig)
+	c.opts.logger.Printf("base signature %s", baseSig)
 
-	for _, user := range c.usernames {
-		for _, pass := range c.passwords {
-			s, err := c.poster.Try(user, pass)
-			if err != nil {
-				return err
-			}
-			c.logger.Printf("(%s|%s) %s", user, pass, s)
+	var pairs []pair
+	for _, user := range c.opts.usernames {
+		for _, pass := range c.opts.passwords {
+			pairs = append(pairs, pair{user, pass})
+		}
+	}
 
-			if s.IsCandidate(baseSig) {
-				c.candidates = append(c.candidates, fmt.Sprintf("%s|%s", user, pass))
+	found := make([]bool, len(pairs))
+	jobs := make(chan int)
+	errs := make(chan error, c.opts.workers)
+	var wg sync.WaitGroup
+	for i := 0; i < c.opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for first := true; ; first = false {
+				j, ok := <-jobs
+				if !ok {
+					return
+				}
+				// The run was cancelled while waiting.
+				if !first && c.sleep(ctx) != nil {
+					return
+				}
+				if err := c.try(ctx, pairs[j], baseSig, &found[j]); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
 			}
-			time.Sleep(c.wait() * time.Second)
+		}()
+	}
+feed:
+	for j := range pairs {
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	for j, p := range pairs {
+		if found[j] {
+			c.candidates = append(c.candidates, fmt.Sprintf("%s|%s", p.user, p.pass))
 		}
 	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
 
+func (c *Candidater) try(ctx context.Context, p pair, baseSig *Signature, found *bool) error {
+	s, err := c.poster.Try(ctx, p.user, p.pass)
+	if err != nil {
+		return err
+	}
+	c.opts.logger.Printf("(%s|%s) %s", p.user, p.pass, s)
+	*found = s.IsCandidate(baseSig)
 	return nil
 }
 
+// sleep waits between two tries of a worker.
+func (c *Candidater) sleep(ctx context.Context) error {
+	select {
+	case <-time.After(c.wait()):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Candidater) wait() time.Duration {
-	wait := c.waitTime + rand.Intn(c.jitter)
-	return time.Duration(wait)
+	wait := c.opts.wait
+	if c.opts.jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(c.opts.jitter)))
+	}
+	return wait
 }
 
 var source = rand.NewSource(time.Now().UnixNano())
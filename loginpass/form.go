@@ -1,8 +1,11 @@
-package main
+package loginpass
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -15,6 +18,8 @@ type Input struct {
 	Name, Value string
 }
 
+// POST is a login form: where it is posted, the names of its username,
+// password and CSRF token inputs, and its other inputs.
 type POST struct {
 	URL         string
 	Referer     string
@@ -29,14 +34,20 @@ type POST struct {
 
 type formPoster struct {
 	post *POST
+	opts *options
 }
 
-func (fp *formPoster) Try(username, pass string) (*Signature, error) {
-	token, cookie, err := fp.refreshAuthenticityTokenAndCookie()
+// NewFormPoster returns a Poster trying credentials by posting the
+// form post, with a fresh CSRF token and cookie for each try.
+func NewFormPoster(post *POST, opts ...Option) Poster {
+	return &formPoster{post: post, opts: newOptions(opts)}
+}
+
+func (fp *formPoster) Try(ctx context.Context, username, pass string) (*Signature, error) {
+	token, cookie, err := fp.refreshAuthenticityTokenAndCookie(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("grabing cookie and token: %s", err)
 	}
-	fp.post.TokenVal = token
 
 	u, err := url.ParseRequestURI(fp.post.URL)
 	if err != nil {
@@ -46,7 +57,7 @@ func (fp *formPoster) Try(username, pass string) (*Signature, error) {
 	if fp.post.ActionPath != "" && !strings.HasPrefix(fp.post.ActionPath, "http") {
 		u.Path = fp.post.ActionPath
 	}
-	verbose("Posting at %s", u)
+	fp.opts.verbosef("Posting at %s", u)
 
 	var body string
 	if fp.post.ContentType == "application/json" {
@@ -64,8 +75,8 @@ func (fp *formPoster) Try(username, pass string) (*Signature, error) {
 	} else {
 		form := url.Values{}
 		if token != "" {
-			verbose("Set authenticity token %s", token)
-			form.Set(fp.post.TokenName, fp.post.TokenVal)
+			fp.opts.verbosef("Set authenticity token %s", token)
+			form.Set(fp.post.TokenName, token)
 		}
 		form.Set(fp.post.Username, username)
 		form.Set(fp.post.Password, pass)
@@ -76,10 +87,13 @@ func (fp *formPoster) Try(username, pass string) (*Signature, error) {
 		body = form.Encode()
 	}
 
-	req, err := http.NewRequest("POST", u.String(), strings.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Add("Content-Length", strconv.Itoa(len(body)))
 	req.Header.Add("Accept", "*/*")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:63.0) Gecko/20100101 Firefox/63.0")
+	req.Header.Set("User-Agent", userAgent)
 	if fp.post.ContentType == "application/json" {
 		req.Header.Add("Content-Type", "application/json")
 	} else {
@@ -90,18 +104,34 @@ func (fp *formPoster) Try(username, pass string) (*Signature, error) {
 	}
 
 	if cookie != nil {
-		verbose("Set cookie %s=%s", cookie.Name, cookie.Value)
+		fp.opts.verbosef("Set cookie %s=%s", cookie.Name, cookie.Value)
 		req.AddCookie(cookie)
 	}
 
-	sig, _, err := sendRequest(req)
+	sig, _, err := fp.opts.send(req)
+	if err != nil {
+		return nil, err
+	}
 	sig.Username = username
 
-	return sig, err
+	return sig, nil
+}
+
+// requestsPerTry counts the request refreshing the token and cookie.
+func (fp *formPoster) requestsPerTry() int {
+	return 2
 }
 
-func (c *formPoster) refreshAuthenticityTokenAndCookie() (string, *http.Cookie, error) {
-	res, err := http.Get(c.post.URL)
+func (c *formPoster) refreshAuthenticityTokenAndCookie(ctx context.Context) (string, *http.Cookie, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.post.URL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := c.opts.limit(req.Context()); err != nil {
+		return "", nil, err
+	}
+	defer c.opts.acquire(req.URL.Host)()
+	res, err := c.opts.client.Do(req)
 	if err != nil {
 		return "", nil, err
 	}
@@ -127,3 +157,82 @@ func (c *formPoster) refreshAuthenticityTokenAndCookie() (string, *http.Cookie,
 
 	return tok, cookie, nil
 }
+
+// FormFromURL returns the login form of the page at pageURL, the form
+// with a password input.
+func FormFromURL(pageURL string, opts ...Option) (*POST, error) {
+	if pageURL == "" {
+		return nil, errors.New("create form: missing url")
+	}
+	res, err := newOptions(opts).client.Get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+	return FormFromHTML(pageURL, res.Body)
+}
+
+// FormFromHTML returns the login form of the page at pageURL read from
+// html, as when the page is rendered by a browser.
+func FormFromHTML(pageURL string, html io.Reader) (*POST, error) {
+	doc, err := goquery.NewDocumentFromReader(html)
+	if err != nil {
+		return nil, err
+	}
+
+	csrfParam := doc.Find("head > meta[name='csrf-param']").First()
+	csrfToken := doc.Find("head > meta[name='csrf-token']").First()
+
+	param, _ := csrfParam.Attr("content")
+	tok, _ := csrfToken.Attr("content")
+
+	post := &POST{
+		URL:       pageURL,
+		TokenName: param,
+		TokenVal:  tok,
+	}
+
+	var form *goquery.Selection
+	passwordSection := doc.Find("input[type='password']").First()
+	passwordSection.Parents().Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "form" {
+			action, _ := s.Attr("action")
+			if u, err := url.Parse(action); err == nil {
+				post.ActionPath = u.Path
+			}
+			form = s
+		}
+	})
+
+	if form == nil {
+		return nil, fmt.Errorf("no form found at %s", pageURL)
+	}
+
+	var inputs []Input
+	form.Find("input").Each(func(i int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		value, _ := s.Attr("value")
+		inputs = append(inputs, Input{Name: name, Value: value})
+	})
+
+	for _, in := range inputs {
+		if strings.Contains(in.Name, "pass") {
+			post.Password = in.Name
+			continue
+		}
+		if strings.Contains(in.Name, "log") || strings.Contains(in.Name, "name") || strings.Contains(in.Name, "mail") {
+			post.Username = in.Name
+			continue
+		}
+		if (in.Value != "" && in.Name != "") && in.Name != "authenticity_token" {
+			post.ExtraInputs = append(post.ExtraInputs, in)
+		}
+
+	}
+
+	return post, nil
+}
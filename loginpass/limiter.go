@@ -0,0 +1,105 @@
+package loginpass
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket lets through requests at a sustained rate of one per
+// interval, with bursts of up to burst requests.
+type tokenBucket struct {
+	once     sync.Once
+	interval time.Duration
+	burst    int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(requests int, per time.Duration, burst int) *tokenBucket {
+	b := new(tokenBucket)
+	b.configure(requests, per, burst)
+	return b
+}
+
+// configure sets the rate of b the first time it is called, so that
+// the options sharing b keep the first rate.
+func (b *tokenBucket) configure(requests int, per time.Duration, burst int) {
+	b.once.Do(func() {
+		if burst < 1 {
+			burst = 1
+		}
+		b.interval, b.burst, b.tokens = per/time.Duration(requests), burst, float64(burst)
+	})
+}
+
+// reserve takes a token and returns how long to wait before it is
+// available. Tokens go negative while waiters queue up.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens += float64(now.Sub(b.last)) / float64(b.interval)
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(b.interval))
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delay := b.reserve(time.Now())
+	if delay == 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// duration returns the time n requests take at the sustained rate
+// once the burst is spent.
+func (b *tokenBucket) duration(n int) time.Duration {
+	if n <= b.burst {
+		return 0
+	}
+	return time.Duration(n-b.burst) * b.interval
+}
+
+// hostSemaphores caps the number of requests in flight to each host.
+type hostSemaphores struct {
+	max int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func (s *hostSemaphores) acquire(host string) (release func()) {
+	s.mu.Lock()
+	sem, ok := s.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, s.max)
+		s.hosts[host] = sem
+	}
+	s.mu.Unlock()
+
+	sem <- struct{}{}
+	return func() { <-sem }
+}
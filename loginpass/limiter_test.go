@@ -0,0 +1,25 @@
+package loginpass
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(2, time.Second, 1)
+	start := time.Now()
+	for _, test := range []struct {
+		at   time.Duration
+		want time.Duration
+	}{
+		{0, 0},
+		{0, 500 * time.Millisecond},
+		{0, time.Second},
+		{3 * time.Second, 0},
+		{3 * time.Second, 500 * time.Millisecond},
+	} {
+		if got := b.reserve(start.Add(test.at)); got != test.want {
+			t.Errorf("at %s: got %s, want %s", test.at, got, test.want)
+		}
+	}
+}
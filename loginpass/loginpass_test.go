@@ -0,0 +1,231 @@
+package loginpass_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simcap/auditools/loginpass"
+)
+
+const loginPage = `<html><head><meta name="csrf-param" content="authenticity_token"><meta name="csrf-token" content="tok"></head>
+<body><form action="/session" method="post">
+<input type="hidden" name="authenticity_token" value="tok">
+<input type="hidden" name="remember" value="1">
+<input type="text" name="login">
+<input type="password" name="password">
+</form></body></html>`
+
+// newServer returns a test site accepting admin|secret, and the
+// maximum number of requests it served concurrently.
+func newServer() (*httptest.Server, *int32) {
+	var inFlight, maxInFlight int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, loginPage)
+	})
+	mux.HandleFunc("POST /session", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("authenticity_token") != "tok" {
+			http.Error(w, "invalid token", http.StatusForbidden)
+			return
+		}
+		if r.FormValue("login") == "admin" && r.FormValue("password") == "secret" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	})
+	mux.HandleFunc("GET /basic", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for max := atomic.LoadInt32(&maxInFlight); n > max && !atomic.CompareAndSwapInt32(&maxInFlight, max, n); max = atomic.LoadInt32(&maxInFlight) {
+		}
+		time.Sleep(time.Millisecond)
+		mux.ServeHTTP(w, r)
+	})), &maxInFlight
+}
+
+func TestFormFromURL(t *testing.T) {
+	srv, _ := newServer()
+	defer srv.Close()
+
+	post, err := loginpass.FormFromURL(srv.URL, loginpass.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &loginpass.POST{
+		URL:         srv.URL,
+		ActionPath:  "/session",
+		Username:    "login",
+		Password:    "password",
+		TokenName:   "authenticity_token",
+		TokenVal:    "tok",
+		ExtraInputs: []loginpass.Input{{Name: "remember", Value: "1"}},
+	}
+	if !reflect.DeepEqual(post, want) {
+		t.Fatalf("got %+v, want %+v", post, want)
+	}
+}
+
+func TestCandidater(t *testing.T) {
+	srv, maxInFlight := newServer()
+	defer srv.Close()
+
+	post, err := loginpass.FormFromURL(srv.URL, loginpass.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []loginpass.Option{
+		loginpass.WithHTTPClient(srv.Client()),
+		loginpass.WithUsernames("root", "admin", "guest"),
+		loginpass.WithPasswords("admin", "secret", "guest", "123456"),
+		loginpass.WithWait(0, 0),
+		loginpass.WithWorkers(4),
+		loginpass.WithRateLimit(1000, time.Second),
+		loginpass.WithBurst(4),
+		loginpass.WithHostConcurrency(2),
+	}
+	for name, poster := range map[string]loginpass.Poster{
+		"form":      loginpass.NewFormPoster(post, opts...),
+		"basicauth": loginpass.NewBasicAuthPoster(srv.URL+"/basic", opts...),
+	} {
+		c := loginpass.NewCandidater(poster, opts...)
+		if err := c.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.Candidates(), []string{"admin|secret"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+	if got := atomic.LoadInt32(maxInFlight); got > 2 {
+		t.Errorf("got %d requests in flight, want at most 2", got)
+	}
+}
+
+func TestEstimatedMaxTime(t *testing.T) {
+	form := loginpass.NewFormPoster(&loginpass.POST{})
+	opts := []loginpass.Option{
+		loginpass.WithUsernames("root", "admin"),
+		loginpass.WithPasswords("admin", "secret", "guest"),
+		loginpass.WithWorkers(2),
+	}
+	wait := loginpass.WithWait(time.Second, time.Second)
+	for _, test := range []struct {
+		poster loginpass.Poster
+		opts   []loginpass.Option
+		want   time.Duration
+	}{
+		{nil, []loginpass.Option{wait}, 4 * time.Second},
+		{nil, nil, 20 * time.Second},
+		{nil, []loginpass.Option{wait, loginpass.WithRateLimit(1, time.Second)}, 6 * time.Second},
+		{nil, []loginpass.Option{loginpass.WithRateLimit(30, time.Minute)}, 12 * time.Second},
+		{nil, []loginpass.Option{loginpass.WithRateLimit(30, time.Minute), loginpass.WithBurst(3)}, 8 * time.Second},
+		{form, []loginpass.Option{loginpass.WithRateLimit(30, time.Minute)}, 26 * time.Second},
+	} {
+		c := loginpass.NewCandidater(test.poster, append(opts, test.opts...)...)
+		if got := c.EstimatedMaxTime(); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestRateLimitCountsRequests(t *testing.T) {
+	srv, _ := newServer()
+	defer srv.Close()
+
+	post, err := loginpass.FormFromURL(srv.URL, loginpass.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []loginpass.Option{
+		loginpass.WithHTTPClient(srv.Client()),
+		loginpass.WithUsernames("admin"),
+		loginpass.WithPasswords("secret"),
+		loginpass.WithRateLimit(20, time.Second),
+	}
+	c := loginpass.NewCandidater(loginpass.NewFormPoster(post, opts...), opts...)
+	start := time.Now()
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The base signature and the try both send a GET and a POST, the
+	// first one without waiting.
+	if got, want := time.Since(start), 3*50*time.Millisecond; got < want {
+		t.Errorf("got %s, want at least %s", got, want)
+	}
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRunCancelledWhileRateLimited(t *testing.T) {
+	srv, _ := newServer()
+	defer srv.Close()
+
+	var sent int32
+	client := srv.Client()
+	transport := client.Transport
+	client.Transport = roundTripper(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&sent, 1)
+		return transport.RoundTrip(req)
+	})
+	opts := []loginpass.Option{
+		loginpass.WithHTTPClient(client),
+		loginpass.WithUsernames("root", "admin"),
+		loginpass.WithPasswords("admin", "secret"),
+		loginpass.WithWorkers(4),
+		loginpass.WithRateLimit(1, time.Hour),
+	}
+	c := loginpass.NewCandidater(loginpass.NewBasicAuthPoster(srv.URL+"/basic", opts...), opts...)
+
+	// The base signature takes the only token, the workers then block
+	// on the bucket until the run is cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := time.Since(start); got > time.Second {
+		t.Errorf("run took %s after being cancelled", got)
+	}
+	if got := atomic.LoadInt32(&sent); got != 1 {
+		t.Errorf("got %d requests sent, want 1", got)
+	}
+}
+
+func TestPosterKeepsCheckRedirect(t *testing.T) {
+	srv, _ := newServer()
+	defer srv.Close()
+
+	post, err := loginpass.FormFromURL(srv.URL, loginpass.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checked int
+	client := srv.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		checked++
+		return http.ErrUseLastResponse
+	}
+	sig, err := loginpass.NewFormPoster(post, loginpass.WithHTTPClient(client)).Try(context.Background(), "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 1 || sig.StatusCode != http.StatusFound || sig.RedirectCount != 0 {
+		t.Fatalf("got %d checks, status %d and %d redirects, want 1, %d and 0", checked, sig.StatusCode, sig.RedirectCount, http.StatusFound)
+	}
+}
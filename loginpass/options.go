@@ -0,0 +1,147 @@
+package loginpass
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Option configures posters and candidaters.
+type Option func(*options)
+
+type options struct {
+	client    *http.Client
+	logger    *log.Logger
+	verbose   bool
+	usernames []string
+	passwords []string
+	wait      time.Duration
+	jitter    time.Duration
+	waitSet   bool
+	workers   int
+	requests  int
+	per       time.Duration
+	burst     int
+	limiter   *tokenBucket
+	hosts     *hostSemaphores
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		client:  http.DefaultClient,
+		logger:  log.New(io.Discard, "", 0),
+		workers: 1,
+		burst:   1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	// The rate limit spaces requests by itself, waits only stack on it.
+	if !o.waitSet && o.limiter == nil {
+		o.wait, o.jitter = 5*time.Second, 5*time.Second
+	}
+	if o.limiter != nil {
+		o.limiter.configure(o.requests, o.per, o.burst)
+	}
+	return o
+}
+
+// WithHTTPClient sets the client sending requests, http.DefaultClient
+// by default. Its CheckRedirect policy applies, and the redirects it
+// follows are counted.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) { o.client = c }
+}
+
+// WithLogger sets the logger of progress, nothing is logged by default.
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) { o.logger = l }
+}
+
+// WithVerbose logs the requests sent and the responses received.
+func WithVerbose(verbose bool) Option {
+	return func(o *options) { o.verbose = verbose }
+}
+
+// WithUsernames sets the usernames candidaters try.
+func WithUsernames(usernames ...string) Option {
+	return func(o *options) { o.usernames = usernames }
+}
+
+// WithPasswords sets the passwords candidaters try for each username.
+func WithPasswords(passwords ...string) Option {
+	return func(o *options) { o.passwords = passwords }
+}
+
+// WithWait sets the time each worker of candidaters waits between two
+// tries, plus a random jitter up to jitter. Defaults to 5s and 5s, or
+// no wait when rate limited.
+func WithWait(wait, jitter time.Duration) Option {
+	return func(o *options) { o.wait, o.jitter, o.waitSet = wait, jitter, true }
+}
+
+// WithWorkers sets the number of tries candidaters run concurrently,
+// 1 by default.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// WithRateLimit limits the HTTP requests sent to requests per duration,
+// as in WithRateLimit(30, time.Minute), across all the posters and
+// candidaters sharing the option. Requests are not limited by default.
+func WithRateLimit(requests int, per time.Duration) Option {
+	limiter := new(tokenBucket)
+	return func(o *options) {
+		if requests > 0 && per > 0 {
+			o.requests, o.per = requests, per
+			o.limiter = limiter
+		}
+	}
+}
+
+// WithBurst lets rate limited posters send up to n requests at once
+// before the rate applies, 1 by default.
+func WithBurst(n int) Option {
+	return func(o *options) { o.burst = n }
+}
+
+// WithHostConcurrency caps the requests in flight to each host at n,
+// across all the posters sharing the option.
+func WithHostConcurrency(n int) Option {
+	hosts := &hostSemaphores{max: n, hosts: make(map[string]chan struct{})}
+	return func(o *options) {
+		if n > 0 {
+			o.hosts = hosts
+		}
+	}
+}
+
+// acquire waits for a request to host to be allowed under
+// WithHostConcurrency, and returns the function releasing it.
+func (o *options) acquire(host string) (release func()) {
+	if o.hosts == nil {
+		return func() {}
+	}
+	return o.hosts.acquire(host)
+}
+
+// limit waits for a request to be allowed under WithRateLimit or ctx
+// to be done.
+func (o *options) limit(ctx context.Context) error {
+	if o.limiter == nil {
+		return nil
+	}
+	return o.limiter.Wait(ctx)
+}
+
+func (o *options) verbosef(msg string, a ...interface{}) {
+	if o.verbose {
+		o.logger.Printf(msg, a...)
+	}
+}
@@ -1,12 +1,23 @@
-package main
+// Package loginpass tries username and password pairs against a login
+// form or basic authentication, and reports the pairs whose responses
+// differ from the response to random credentials.
+package loginpass
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
 	"time"
 )
 
+// Poster tries credentials and returns the signature of the response.
+// The requests of a try stop when ctx is done.
 type Poster interface {
-	Try(username, pass string) (*Signature, error)
+	Try(ctx context.Context, username, pass string) (*Signature, error)
 }
 
 type Signature struct {
@@ -33,3 +44,62 @@ func (s Signature) IsCandidate(base *Signature) bool {
 	}
 	return false
 }
+
+func (o *options) send(req *http.Request) (*Signature, *http.Response, error) {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	o.verbosef("------------------------------------------------\n%s\n--------------------------------------------", dump)
+
+	var serverStart, serverDone time.Time
+	trace := &httptrace.ClientTrace{
+		WroteRequest:         func(info httptrace.WroteRequestInfo) { serverStart = time.Now() },
+		GotFirstResponseByte: func() { serverDone = time.Now() },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	var redirectCount int
+	client := *o.client
+	check := o.client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if check != nil {
+			if err := check(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		redirectCount++
+		o.verbosef("Redirecting to %s (%d)", req.URL, len(via))
+		return nil
+	}
+	if err := o.limit(req.Context()); err != nil {
+		return nil, nil, err
+	}
+	defer o.acquire(req.URL.Host)()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, resp, err
+	}
+	defer resp.Body.Close()
+
+	o.verbosef("-> Response %s\n", resp.Status)
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(b) < 200 {
+		o.verbosef("----------- Response Body ---------\n%s\n-----------------------------", b)
+	}
+
+	sig := &Signature{
+		RedirectCount:        redirectCount,
+		StatusCode:           resp.StatusCode,
+		ResponseSize:         len(b),
+		ServerProcessingTime: serverDone.Sub(serverStart),
+	}
+
+	return sig, resp, nil
+}